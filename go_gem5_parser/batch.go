@@ -0,0 +1,183 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"sync"
+
+	"go_gem5_parser/gem5stats"
+)
+
+// findRuns expands the directory globs into the stats files (stats.txt or
+// stats.txt.gz) of the runs, sorted and without duplicates. A match may also
+// name the stats file itself.
+func findRuns(patterns []string) []string {
+	seen := make(map[string]bool)
+	var files []string
+	for _, pattern := range patterns {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			log.Fatalf("invalid directory pattern %q: %v", pattern, err)
+		}
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				continue
+			}
+			file := match
+			if info.IsDir() {
+				file = filepath.Join(match, "stats.txt")
+				if !fileExists(file) {
+					file += ".gz"
+				}
+				if !fileExists(file) {
+					continue
+				}
+			}
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// runLabel names a run by a config.json parameter when labelKey is set and
+// found, and by its directory otherwise.
+func runLabel(dir string, labelKey string) (string, error) {
+	if labelKey != "" {
+		if file, err := os.Open(filepath.Join(dir, "config.json")); err == nil {
+			defer file.Close()
+			value, ok, err := gem5stats.Gem5ConfigValue(file, labelKey)
+			if err != nil {
+				return "", err
+			}
+			if ok {
+				return value, nil
+			}
+		}
+	}
+	return filepath.Base(dir), nil
+}
+
+// analyseRun runs the TMA analysis of one stats file and keeps its last dump,
+// which covers the whole run unless the stats were reset between dumps. With
+// reset the TMA is that of all dumps summed instead.
+func analyseRun(file string, Interests *gem5stats.Interest, ParamsFile string, ProfileName string, labelKey string, reset bool) (gem5stats.BatchRun, error) {
+	dir := filepath.Dir(file)
+	run := gem5stats.BatchRun{Label: filepath.Base(dir), Dir: dir}
+
+	Params, err := readParams(file, "", ParamsFile)
+	if err != nil {
+		return run, err
+	}
+	// The table has no room for the profile of each run, the warnings tell
+	// about stats it could not find.
+	Dumps, _, err := analyseStats(file, Interests, Params, ProfileName)
+	if err != nil {
+		return run, err
+	}
+	run.Dump = Dumps[len(Dumps)-1]
+	if reset {
+		run.Dump.Stats = gem5stats.SumDumps(Dumps, Params)
+	}
+	run.Label, err = runLabel(dir, labelKey)
+	if err != nil {
+		return run, fmt.Errorf("%s: %w", dir, err)
+	}
+	return run, nil
+}
+
+// analyseRuns runs analyseRun for every stats file on up to jobs goroutines.
+// A run that fails keeps its error in Err, the others are still analysed.
+func analyseRuns(files []string, Interests *gem5stats.Interest, ParamsFile string, ProfileName string, labelKey string, reset bool, jobs int) []gem5stats.BatchRun {
+	runs := make([]gem5stats.BatchRun, len(files))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < jobs; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				run, err := analyseRun(files[i], Interests, ParamsFile, ProfileName, labelKey, reset)
+				run.Err = err
+				runs[i] = run
+			}
+		}()
+	}
+	for i := range files {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return runs
+}
+
+// runBatch implements "go_gem5_parser batch [flags] <m5out glob>...".
+func runBatch(args []string) {
+	flags := flag.NewFlagSet("batch", flag.ExitOnError)
+	var InterestFile = flags.String("interest", "interests.txt", "The (relative path to) file that contain interested data")
+	var OutFile = flags.String("out", "batch.md", "The (relative path to) the output file")
+	var Format = flags.String("format", "Markdown", "The output format: Markdown, CSV or HTML")
+	var ParamsFile = flags.String("uarch", "", "The (relative path to) JSON file with the microarchitecture parameters of the TMA model")
+	var ProfileName = flags.String("profile", "auto", "The stat name profile: auto, stdlib-ruby, stdlib-classic, se-classic or the path to a JSON profile")
+	var Label = flags.String("label", "", "The config.json parameter (e.g. board.cache_hierarchy.l2caches.size) that labels the runs, defaults to the directory name")
+	var Reset = flags.Bool("reset", false, "Stats were reset after every dump (m5_dumpreset_stats), sum the counters of all dumps for the TMA of a run; the selected stats are still those of the last dump")
+	var Jobs = flags.Int("jobs", runtime.NumCPU(), "The number of runs analysed concurrently")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go_gem5_parser batch [flags] <m5out directory glob>...")
+		fmt.Fprintln(flags.Output(), "Each run is tabulated by its last dump, which covers the whole run only when")
+		fmt.Fprintln(flags.Output(), "the stats were never reset; pass -reset when every dump holds its own interval.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	if *Jobs < 1 {
+		*Jobs = 1
+	}
+
+	writer, err := gem5stats.NewBatchWriter(*Format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	Interests := loadInterest(InterestFile)
+
+	files := findRuns(flags.Args())
+	if len(files) == 0 {
+		log.Fatal("No stats.txt or stats.txt.gz found in the given directories!")
+	}
+	fmt.Println("Found", len(files), "runs.")
+	fmt.Println()
+
+	runs := analyseRuns(files, Interests, *ParamsFile, *ProfileName, *Label, *Reset, *Jobs)
+	gem5stats.PrintBatch(os.Stdout, runs)
+	failed := 0
+	for _, run := range runs {
+		if run.Err != nil {
+			failed++
+		}
+	}
+	if failed == len(runs) {
+		log.Fatal("None of the runs could be analysed!")
+	}
+
+	file, err := os.Create(*OutFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := writer(file, runs); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"go_gem5_parser/gem5stats"
+)
+
+// pickDump returns the dump with the given 1-based index, or the last dump
+// (the whole run) when index is 0.
+func pickDump(dumps []gem5stats.Dump, index int, file string) gem5stats.Dump {
+	if index == 0 {
+		return dumps[len(dumps)-1]
+	}
+	if index < 0 || index > len(dumps) {
+		log.Fatalf("%s has %d dumps, there is no dump %d", file, len(dumps), index)
+	}
+	return dumps[index-1]
+}
+
+// runDiff implements "go_gem5_parser diff [flags] before/stats.txt after/stats.txt".
+func runDiff(args []string) {
+	flags := flag.NewFlagSet("diff", flag.ExitOnError)
+	var InterestFile = flags.String("interest", "interests.txt", "The (relative path to) file that contain interested data")
+	var OutFile = flags.String("out", "diff.md", "The (relative path to) the output file")
+	var Format = flags.String("format", "Markdown", "The output format: Markdown, CSV or JSON")
+	var DumpIndex = flags.Int("dump", 0, "The dump to compare (1-based), 0 compares the last dump")
+	var ParamsFile = flags.String("uarch", "", "The (relative path to) JSON file with the microarchitecture parameters of the TMA model")
+	var ProfileName = flags.String("profile", "auto", "The stat name profile: auto, stdlib-ruby, stdlib-classic, se-classic or the path to a JSON profile")
+	var ConfigFile = flags.String("config", "", "The (relative path to) gem5 config.json used for both runs, defaults to config.json next to each stats file")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: go_gem5_parser diff [flags] <before stats.txt> <after stats.txt>")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 2 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	Interests := loadInterest(InterestFile)
+
+	files := []string{flags.Arg(0), flags.Arg(1)}
+	var picked [2]gem5stats.Dump
+	for i := range files {
+		Params := loadParams(&files[i], ConfigFile, ParamsFile)
+		Dumps := analyseFile(Interests, &files[i], Params, ProfileName)
+		picked[i] = pickDump(Dumps, *DumpIndex, files[i])
+	}
+
+	report := &gem5stats.DiffReport{Before: files[0], After: files[1]}
+	report.Stats, report.TMA = gem5stats.DiffDumps(picked[0], picked[1])
+	gem5stats.PrintTMADiff(os.Stdout, report.TMA)
+
+	file, err := os.Create(*OutFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	if err := gem5stats.WriteDiff(file, report, *Format); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,752 @@
+package gem5stats
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"path"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CacheStats holds the access statistics for a cache level.
+type CacheStats struct {
+	Access   uint64  `json:"access"`    // Total number of cache accesses (Hits + Misses)
+	Hits     uint64  `json:"hits"`      // Total number of cache hits
+	Misses   uint64  `json:"misses"`    // Total number of cache misses
+	MissRate float64 `json:"miss_rate"` // MissRate of Cache
+
+	MissLatency uint64 `json:"miss_latency"` // Total ticks spent on demand misses, classic caches only
+}
+
+// ThreadData holds statistics specific to a hardware thread context.
+type ThreadData struct {
+	NumInsts     uint64 `json:"num_insts"`     // Total number of instructions committed by this thread
+	NumOps       uint64 `json:"num_ops"`       // Total number of micro-ops (uOps) committed by this thread
+	SlotsRetired uint64 `json:"slots_retired"` // Instructions committed by this thread, as counted by committedInstType_N
+	OpsExecuted  uint64 `json:"ops_executed"`  // Micro-ops executed for this thread
+}
+
+// PMUStats aggregates hardware performance counters from the simulation.
+type PMUStats struct {
+	Hierarchy string `json:"hierarchy"` // Memory system of the simulation, taken from the stat profile
+
+	// --- Base Timing ---
+	Cycles   uint64 `json:"cycles"`    // Total CPU execution cycles (Clocks)
+	Simticks uint64 `json:"sim_ticks"` // Total simulation time in ticks (1 tick = 1ps usually)
+
+	//
+
+	// --- Pipeline Slot Metrics (Top-Down Base) ---
+	SlotsIssued  uint64 `json:"slots_issued"`  // Total number of instructions issued to the backend (Dispatched)
+	SlotsRetired uint64 `json:"slots_retired"` // Total number of instructions committed (Retired)
+
+	// --- Pipeline Stalls & Bubbles ---
+	MispredRetired  uint64 `json:"mispred_retired"`   // Count of retired branch mispredictions
+	FetchCycles     uint64 `json:"fetch_cycles"`      // Cycles stalled due to I-Cache miss response latency
+	RecoveryCycles  uint64 `json:"recovery_cycles"`   // Cycles stalled due to pipeline squashes (Branch Recovery)
+	MachineClears   uint64 `json:"machine_clears"`    // Cycles stalled due to machine clears (e.g., memory ordering flushes)
+	FetchStallSlots uint64 `json:"fetch_stall_slots"` // Total Slots on the Pipeline that have been stalled
+
+	// --- Execution Unit Metrics ---
+	OpsExecuted uint64 `json:"ops_executed"` // Total number of micro-ops executed in execution units
+	DivOps      uint64 `json:"div_ops"`      // Committed integer/float divide and square root instructions
+
+	// --- Structural Stalls (Event Counts) ---
+	LoadQueueFull  uint64 `json:"load_queue_full"`  // Count of events where the Load Queue (LQ) was full
+	StoreQueueFull uint64 `json:"store_queue_full"` // Count of events where the Store Queue (SQ) was full
+	InstQueueFull  uint64 `json:"inst_queue_full"`  // Count of events where the Instruction Queue (IQ) was full
+
+	// --- Load/Store Unit Specifics ---
+	LSQBlockedByCache  uint64  `json:"lsq_blocked_by_cache"`  // Count of times LSQ was blocked by cache ports or contention
+	MeanLoadAccessTime float64 `json:"mean_load_access_time"` // Average latency in cycles from Load issue to data return
+
+	// --- Memory Hierarchy (DRAM) ---
+	MemReadReqs        uint64 `json:"mem_read_reqs"`         // Total number of read requests sent to the memory controller
+	MemQueueStallCount uint64 `json:"mem_queue_stall_count"` // Count of stalls in the Ruby mandatory queue (Protocol/Contention stalls), or of L1D accesses blocked for lack of MSHRs
+
+	// --- Cache Hierarchy Stats ---
+	MemLevelParallel float64    `json:"mem_level_parallel"` // The level of parallelism that the memory has, match ruby_system.m_outstandReqHistSeqr::mean
+	L1D              CacheStats `json:"l1d"`                // L1 Data Cache statistics
+	L1I              CacheStats `json:"l1i"`                // L1 Instruction Cache statistics
+	L2               CacheStats `json:"l2"`                 // L2 Unified Cache statistics
+	L3               CacheStats `json:"l3"`                 // L3 Cache statistics, zero when the L2 is the last level
+
+	// --- Thread Context Stats ---
+	Threads []ThreadData `json:"threads"` // Statistics for every hardware thread context of the core
+
+	// --- Instruction Mix ---
+	InstMix map[string]uint64 `json:"inst_mix"` // Committed instructions per op class (IntAlu, MemRead, ...)
+
+	// Processed Stats Here:
+
+}
+
+type TMAOutStats struct {
+	L1_retire           float64 `json:"retiring"`
+	L1_badspec          float64 `json:"bad_speculation"`
+	L1_frontend         float64 `json:"frontend_bound"`
+	L1_backend          float64 `json:"backend_bound"`
+	L0_fullfrontend     float64 `json:"full_frontend_bound"`
+	L0_frontendutil     float64 `json:"frontend_util"`
+	L0_BranchPrediction float64 `json:"branch_prediction"`
+
+	undefined map[string]bool // Fields gem5 wrote as nan or inf, by field name
+}
+
+type L1TMAStats struct {
+	L1_retire   float64 `json:"retiring"`
+	L1_badspec  float64 `json:"bad_speculation"`
+	L1_frontend float64 `json:"frontend_bound"`
+	L1_backend  float64 `json:"backend_bound"`
+}
+
+type L2TMAStats struct {
+	// Frontend
+	L2_fetch_latency   float64 `json:"fetch_latency"`
+	L2_fetch_bandwidth float64 `json:"fetch_bandwidth"`
+	// Bad Speculation
+	L2_branch_mispredict float64 `json:"branch_mispredict"`
+	L2_machine_clear     float64 `json:"machine_clears"`
+	// Backend
+	L2_memory_bound float64 `json:"memory_bound"`
+	L2_core_bound   float64 `json:"core_bound"`
+}
+
+type L3TMAStats struct {
+	// Memory Bound
+	L3_l1_bound   float64 `json:"l1_bound"`
+	L3_l2_bound   float64 `json:"l2_bound"`
+	L3_l3_bound   float64 `json:"l3_bound"`
+	L3_dram_bound float64 `json:"dram_bound"`
+	// Core Bound
+	L3_divider           float64 `json:"divider"`
+	L3_ports_utilization float64 `json:"ports_utilization"`
+}
+
+type TMAStats struct {
+	core     string // Core label, empty for the aggregated view
+	pmu      *PMUStats
+	params   *UarchParams // Parameters the calculated levels are based on
+	mytma    *TMAOutStats // TMA Stats collected from GEM5 Directly
+	tmaL1    *L1TMAStats  // TMA Stats calculated
+	tmaL2    *L2TMAStats  // Level 2 TMA stats Calculated
+	tmaL3    *L3TMAStats  // Level 3 TMA stats Calculated
+	metrics  *Metrics     // IPC, MPKI and instruction mix
+	warnings []Warning    // Problems found by validateTMA, checkMissing and checkUndefined
+	cores    []*TMAStats  // Per core analysis, only set on the aggregated view
+}
+
+// Interval is the part of the simulation covered by one dump.
+type Interval struct {
+	StartTick uint64
+	EndTick   uint64
+	Stats     *TMAStats // TMA of the interval only, mytma is nil for cumulative deltas
+}
+
+// coreRef locates the stats of one core: prefix replaces {core} and index
+// replaces {n} in the key templates ("" for boards with a single core).
+type coreRef struct {
+	label  string
+	prefix string
+	index  string
+}
+
+// findCores lists every core that has at least one stat in entries, ordered
+// by core index.
+func findCores(entries *map[string]Entry, profile *Profile) []coreRef {
+	seen := make(map[string]coreRef)
+	for name := range *entries {
+		if m := profile.core.FindStringSubmatch(name); m != nil {
+			core := coreRef{prefix: m[1]}
+			if len(m) > 2 {
+				core.index = m[2]
+			}
+			seen[m[1]] = core
+		}
+	}
+
+	cores := make([]coreRef, 0, len(seen))
+	for _, core := range seen {
+		cores = append(cores, core)
+	}
+	sort.Slice(cores, func(i, j int) bool {
+		a, _ := strconv.Atoi(cores[i].index)
+		b, _ := strconv.Atoi(cores[j].index)
+		return a < b
+	})
+	for i := range cores {
+		n, _ := strconv.Atoi(cores[i].index)
+		cores[i].label = fmt.Sprintf("core%d", n)
+	}
+	return cores
+}
+
+func (c coreRef) key(template string) string {
+	return strings.NewReplacer("{core}", c.prefix, "{n}", c.index).Replace(template)
+}
+
+// lookup returns the value of key, summing every matching stat when key is a
+// wildcard (e.g. the banks of a shared cache).
+func lookup(entries *map[string]Entry, key string) (float64, bool) {
+	if !strings.ContainsAny(key, "*?[") {
+		ent, ok := (*entries)[key]
+		return ent.Value, ok
+	}
+
+	sum, found := 0.0, false
+	for name, ent := range *entries {
+		if ok, _ := path.Match(key, name); ok {
+			sum += ent.Value
+			found = true
+		}
+	}
+	return sum, found
+}
+
+func getMyTMA(entries *map[string]Entry, core coreRef, profile *Profile) *TMAOutStats {
+	mytma := new(TMAOutStats)
+	collect(entries, profile.GEM5TMA, mytma, core.key)
+	for field, names := range profile.GEM5TMA {
+		for _, name := range names {
+			if len(undefinedStats(entries, core.key(name))) > 0 {
+				if mytma.undefined == nil {
+					mytma.undefined = make(map[string]bool)
+				}
+				mytma.undefined[field] = true
+			}
+		}
+	}
+	return mytma
+}
+
+// maxThreads bounds the search for thread_N stats of an SMT core.
+const maxThreads = 64
+
+// getThreads collects the per thread counters of a core. A core without any
+// thread stat still gets one (empty) thread context.
+func getThreads(entries *map[string]Entry, core coreRef, profile *Profile) []ThreadData {
+	var threads []ThreadData
+	for t := 0; t < maxThreads; t++ {
+		var thread ThreadData
+		key := func(template string) string {
+			return core.key(strings.ReplaceAll(template, "{t}", strconv.Itoa(t)))
+		}
+		if !collect(entries, profile.Thread, &thread, key) {
+			break
+		}
+		threads = append(threads, thread)
+	}
+	if len(threads) == 0 {
+		threads = append(threads, ThreadData{})
+	}
+	return threads
+}
+
+// Memory systems of gem5, which name their cache stats differently.
+const (
+	HierarchyRuby    = "ruby"
+	HierarchyClassic = "classic"
+)
+
+// divOpClasses are the gem5 op classes executed by the unpipelined divider units.
+var divOpClasses = []string{"IntDiv", "FloatDiv", "FloatSqrt", "SimdDiv", "SimdFloatDiv", "SimdFloatSqrt"}
+
+// classicMLP estimates the memory level parallelism of the classic caches,
+// which have no outstanding request histogram. By Little's law the ticks spent
+// on L1D misses over the elapsed ticks give the mean number of misses in flight.
+func classicMLP(pmu *PMUStats) float64 {
+	if pmu.Simticks == 0 || pmu.L1D.MissLatency == 0 {
+		return 0
+	}
+	return math.Max(1, float64(pmu.L1D.MissLatency)/float64(pmu.Simticks))
+}
+
+// getCorePMU collects the counters private to one core, including its L1 caches.
+func getCorePMU(entries *map[string]Entry, core coreRef, profile *Profile) *PMUStats {
+	pmu := new(PMUStats)
+	collect(entries, profile.CoreStats, pmu, core.key)
+
+	pmu.Threads = getThreads(entries, core, profile)
+	pmu.InstMix = collectMix(entries, profile.InstMix, core.key)
+	for _, thread := range pmu.Threads {
+		pmu.SlotsRetired += thread.SlotsRetired
+		pmu.OpsExecuted += thread.OpsExecuted
+	}
+
+	return pmu
+}
+
+// getSharedPMU collects the counters of resources shared by all cores.
+func getSharedPMU(entries *map[string]Entry, profile *Profile) *PMUStats {
+	pmu := new(PMUStats)
+	pmu.Hierarchy = profile.Hierarchy
+	collect(entries, profile.Shared, pmu, func(key string) string { return key })
+	return pmu
+}
+
+// addCounters adds every uint64 counter of src to dst, recursing into nested
+// structs and counter maps. It is the inverse of subCounters.
+func addCounters(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Uint64:
+		dst.SetUint(dst.Uint() + src.Uint())
+	case reflect.Struct:
+		for i := 0; i < dst.NumField(); i++ {
+			addCounters(dst.Field(i), src.Field(i))
+		}
+	case reflect.Map:
+		if src.Len() == 0 {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMap(dst.Type()))
+		}
+		for _, k := range src.MapKeys() {
+			sum := src.MapIndex(k).Uint()
+			if v := dst.MapIndex(k); v.IsValid() {
+				sum += v.Uint()
+			}
+			dst.SetMapIndex(k, reflect.ValueOf(sum))
+		}
+	}
+}
+
+// attachShared copies the shared counters into a core's PMU. Counters of the
+// shared levels are split by the share of the core in the L1D misses, since
+// those are what reaches the L2 and the memory.
+func attachShared(pmu *PMUStats, shared *PMUStats, share float64) {
+	scale := func(v uint64) uint64 { return uint64(math.Round(float64(v) * share)) }
+
+	pmu.Hierarchy = shared.Hierarchy
+	pmu.Simticks = shared.Simticks
+	pmu.MemLevelParallel = shared.MemLevelParallel
+	if pmu.MemLevelParallel == 0 {
+		pmu.MemLevelParallel = classicMLP(pmu)
+	}
+	pmu.MemReadReqs = scale(shared.MemReadReqs)
+	for _, level := range [][2]*CacheStats{{&pmu.L2, &shared.L2}, {&pmu.L3, &shared.L3}} {
+		level[0].Access = scale(level[1].Access)
+		level[0].Hits = scale(level[1].Hits)
+		level[0].Misses = scale(level[1].Misses)
+	}
+}
+
+// getPMU collects the PMU of every core and the aggregate of all cores, in
+// which the per core counters are summed and the shared ones counted once.
+func getPMU(entries *map[string]Entry, cores []coreRef, profile *Profile) (*PMUStats, []*PMUStats) {
+	shared := getSharedPMU(entries, profile)
+
+	total := new(PMUStats)
+	perCore := make([]*PMUStats, 0, len(cores))
+	for _, core := range cores {
+		pmu := getCorePMU(entries, core, profile)
+		addCounters(reflect.ValueOf(total).Elem(), reflect.ValueOf(pmu).Elem())
+		total.MeanLoadAccessTime += pmu.MeanLoadAccessTime / float64(len(cores))
+		total.Threads = append(total.Threads, pmu.Threads...)
+		perCore = append(perCore, pmu)
+	}
+
+	for _, pmu := range perCore {
+		share := 1.0
+		if len(perCore) > 1 {
+			share = 0
+			if total.L1D.Misses > 0 {
+				share = float64(pmu.L1D.Misses) / float64(total.L1D.Misses)
+			}
+		}
+		attachShared(pmu, shared, share)
+		updateMissRates(pmu)
+	}
+
+	attachShared(total, shared, 1)
+	if shared.MemLevelParallel == 0 && len(perCore) > 0 {
+		// Misses in flight of all cores, the model needs the mean per core.
+		total.MemLevelParallel = 0
+		for _, pmu := range perCore {
+			total.MemLevelParallel += pmu.MemLevelParallel / float64(len(perCore))
+		}
+	}
+	updateMissRates(total)
+
+	return total, perCore
+}
+
+// averageTMA weights the gem5 reported ratios of every core by its cycles.
+func averageTMA(cores []*TMAStats) *TMAOutStats {
+	avg := new(TMAOutStats)
+	var cycles float64
+	for _, core := range cores {
+		cycles += float64(core.pmu.Cycles)
+	}
+	for _, core := range cores {
+		weight := 1.0 / float64(len(cores))
+		if cycles > 0 {
+			weight = float64(core.pmu.Cycles) / cycles
+		}
+		avg.L1_retire += core.mytma.L1_retire * weight
+		avg.L1_badspec += core.mytma.L1_badspec * weight
+		avg.L1_frontend += core.mytma.L1_frontend * weight
+		avg.L1_backend += core.mytma.L1_backend * weight
+		avg.L0_fullfrontend += core.mytma.L0_fullfrontend * weight
+		avg.L0_frontendutil += core.mytma.L0_frontendutil * weight
+		avg.L0_BranchPrediction += core.mytma.L0_BranchPrediction * weight
+		for field := range core.mytma.undefined {
+			if avg.undefined == nil {
+				avg.undefined = make(map[string]bool)
+			}
+			avg.undefined[field] = true
+		}
+	}
+	return avg
+}
+
+func calcL1(pmu *PMUStats, params *UarchParams) *L1TMAStats {
+	stats := new(L1TMAStats)
+	var iWidth = params.IssueWidth
+	var dWidth = params.DispatchWidth
+	var TotalSlots = pmu.Cycles * dWidth
+	var SlotsIssued = pmu.SlotsIssued
+	var SlotsRetired = pmu.SlotsRetired
+	var RecoveryBubbles = pmu.RecoveryCycles * iWidth
+
+	stats.L1_frontend = float64(pmu.FetchStallSlots) / float64(TotalSlots)
+	// 用浮点数相减，Retired 大于 Issued 时不会下溢
+	stats.L1_badspec = (float64(SlotsIssued) - float64(SlotsRetired) + float64(RecoveryBubbles)) / float64(TotalSlots)
+	stats.L1_retire = float64(SlotsRetired) / float64(TotalSlots)
+	stats.L1_backend = 1 - (stats.L1_frontend + stats.L1_badspec + stats.L1_retire)
+
+	return stats
+}
+
+/*
+ * The latencies come from UarchParams, by default
+ * L2 has 8 cycles
+ * Memory has 90 cycles (about 30ns at 3GHZ)
+ */
+
+func calcL2(pmu *PMUStats, l1 *L1TMAStats, params *UarchParams) *L2TMAStats {
+	l2 := new(L2TMAStats)
+
+	// --- 基于 JSON 配置校准的参数 ---
+	var L2Lat = params.L2Lat               // 默认约 7-9 cycles
+	var MemLat = params.memLatency()       // 默认约 80-95 cycles
+	var MLP float64 = pmu.MemLevelParallel // 假设平均内存并行度为 2 (针对乱序核)
+	if MLP <= 0 {
+		// 缺少并行度统计时按 1 处理，validateTMA 会给出警告
+		MLP = 1
+	}
+
+	// 1. Fetch Latency (建议检查 FetchCycles 是否仅包含 I-Cache 停顿)
+	l2.L2_fetch_latency = float64(pmu.FetchCycles) / float64(pmu.Cycles)
+	l2.L2_fetch_bandwidth = math.Max(0, l1.L1_frontend-l2.L2_fetch_latency)
+
+	// 2. Bad Speculation
+	totalBadEvents := float64(pmu.MispredRetired + pmu.MachineClears)
+	if totalBadEvents > 0 {
+		frac := float64(pmu.MispredRetired) / totalBadEvents
+		l2.L2_branch_mispredict = l1.L1_badspec * frac
+		l2.L2_machine_clear = l1.L1_badspec * (1.0 - frac)
+	}
+
+	// 3. Backend Bound - 更加精确的内存建模
+	// 计算 L1 缺失但命中 L2 的次数
+	l1MissL2Hit := float64(pmu.L1D.Misses) - float64(pmu.L2.Misses)
+	if l1MissL2Hit < 0 {
+		l1MissL2Hit = 0
+	}
+
+	// 估算受限周期，引入 MLP 因子防止过大
+	rawMemStall := (l1MissL2Hit * L2Lat) + (float64(pmu.L2.Misses) * MemLat)
+	if pmu.L3.Access > 0 {
+		// 三级缓存结构：L2 缺失中命中 L3 的部分只付 L3 延迟
+		l2MissL3Hit := math.Max(0, float64(pmu.L2.Misses)-float64(pmu.L3.Misses))
+		rawMemStall = (l1MissL2Hit * L2Lat) + (l2MissL3Hit * params.L3Lat) + (float64(pmu.L3.Misses) * MemLat)
+	}
+	adjMemStall := rawMemStall / MLP
+
+	l2.L2_memory_bound = adjMemStall / float64(pmu.Cycles)
+
+	// 归一化限制：Memory Bound 不能超过总 Backend Bound
+	if l2.L2_memory_bound > l1.L1_backend {
+		l2.L2_memory_bound = l1.L1_backend
+	}
+	l2.L2_core_bound = l1.L1_backend - l2.L2_memory_bound
+
+	return l2
+}
+
+// share splits total in proportion to the weights, leaving everything to the
+// fallback slot when no weight is set.
+func share(total float64, weights []float64, fallback int) []float64 {
+	parts := make([]float64, len(weights))
+	sum := 0.0
+	for _, w := range weights {
+		sum += w
+	}
+	if sum <= 0 {
+		parts[fallback] = total
+		return parts
+	}
+	for i, w := range weights {
+		parts[i] = total * w / sum
+	}
+	return parts
+}
+
+func calcL3(pmu *PMUStats, l2 *L2TMAStats, params *UarchParams) *L3TMAStats {
+	l3 := new(L3TMAStats)
+
+	// 1. Memory Bound: 按各级缓存估算的停顿周期拆分，所有权重都以周期为单位
+	l1MissL2Hit := math.Max(0, float64(pmu.L1D.Misses)-float64(pmu.L2.Misses))
+	l2Misses := float64(pmu.L2.Misses)
+
+	// LSQ 被缓存阻塞视为 L1 受限，每次按 lsq_blocked_latency 周期计
+	l1Stall := float64(pmu.LSQBlockedByCache) * params.lsqBlockedLatency()
+	l2Stall := l1MissL2Hit * params.L2Lat
+	l3Stall := 0.0
+	dramStall := l2Misses * params.memLatency()
+	if pmu.L3.Access > 0 {
+		// 三级缓存结构：L2 缺失先查 L3
+		l2MissL3Hit := math.Max(0, l2Misses-float64(pmu.L3.Misses))
+		l3Stall = l2MissL3Hit * params.L3Lat
+		dramStall = float64(pmu.L3.Misses) * params.memLatency()
+	}
+	// 内存控制器队列的停顿也算在 DRAM 上，每次按 mem_queue_latency 周期计
+	dramStall += float64(pmu.MemQueueStallCount) * params.MemQueueLat
+
+	mem := share(l2.L2_memory_bound, []float64{l1Stall, l2Stall, l3Stall, dramStall}, 0)
+	l3.L3_l1_bound, l3.L3_l2_bound, l3.L3_l3_bound, l3.L3_dram_bound = mem[0], mem[1], mem[2], mem[3]
+
+	// 2. Core Bound: 除法器占用周期 vs. IQ 满（发射端口饱和），每次 IQ 满按 iq_full_latency 周期计
+	divStall := float64(pmu.DivOps) * params.DivLat
+	portsStall := float64(pmu.InstQueueFull) * params.IQFullLat
+
+	core := share(l2.L2_core_bound, []float64{divStall, portsStall}, 1)
+	l3.L3_divider, l3.L3_ports_utilization = core[0], core[1]
+
+	return l3
+}
+
+// updateMissRates derives MissRate of every cache level from its counters.
+func updateMissRates(pmu *PMUStats) {
+	CacheList := []*CacheStats{&pmu.L1D, &pmu.L1I, &pmu.L2, &pmu.L3}
+
+	for _, cache := range CacheList {
+		cache.MissRate = 0
+		if cache.Access != 0 {
+			cache.MissRate = float64(cache.Misses) / float64(cache.Access)
+		}
+	}
+}
+
+// subCounters subtracts every uint64 counter of prev from cur, recursing into
+// nested structs. Float fields are means and ratios and are kept from cur.
+func subCounters(cur, prev reflect.Value) {
+	switch cur.Kind() {
+	case reflect.Uint64:
+		if c, p := cur.Uint(), prev.Uint(); c >= p {
+			cur.SetUint(c - p)
+		} else {
+			// The counter went backwards, so the stats were reset in between.
+			cur.SetUint(c)
+		}
+	case reflect.Struct:
+		for i := 0; i < cur.NumField(); i++ {
+			subCounters(cur.Field(i), prev.Field(i))
+		}
+	case reflect.Slice:
+		for i := 0; i < cur.Len() && i < prev.Len(); i++ {
+			subCounters(cur.Index(i), prev.Index(i))
+		}
+	case reflect.Map:
+		for _, k := range cur.MapKeys() {
+			c, p := cur.MapIndex(k).Uint(), uint64(0)
+			if v := prev.MapIndex(k); v.IsValid() {
+				p = v.Uint()
+			}
+			if c >= p {
+				cur.SetMapIndex(k, reflect.ValueOf(c-p))
+			}
+		}
+	}
+}
+
+// since returns the counters accumulated between the prev dump and p.
+func (p *PMUStats) since(prev *PMUStats) *PMUStats {
+	delta := *p
+	delta.Threads = append([]ThreadData(nil), p.Threads...)
+	delta.InstMix = maps.Clone(p.InstMix)
+	subCounters(reflect.ValueOf(&delta).Elem(), reflect.ValueOf(prev).Elem())
+	updateMissRates(&delta)
+	return &delta
+}
+
+// plus returns the counters of p and of the next dump together, for stats
+// that were reset in between. The means are weighted by the cycles of each.
+func (p *PMUStats) plus(next *PMUStats) *PMUStats {
+	sum := *p
+	sum.Threads = append([]ThreadData(nil), p.Threads...)
+	sum.InstMix = maps.Clone(p.InstMix)
+	addCounters(reflect.ValueOf(&sum).Elem(), reflect.ValueOf(next).Elem())
+	for i := 0; i < len(sum.Threads) && i < len(next.Threads); i++ {
+		addCounters(reflect.ValueOf(&sum.Threads[i]).Elem(), reflect.ValueOf(&next.Threads[i]).Elem())
+	}
+	updateMissRates(&sum)
+
+	if sum.Cycles > 0 {
+		mean := func(a, b float64) float64 {
+			return (a*float64(p.Cycles) + b*float64(next.Cycles)) / float64(sum.Cycles)
+		}
+		sum.MeanLoadAccessTime = mean(p.MeanLoadAccessTime, next.MeanLoadAccessTime)
+		sum.MemLevelParallel = mean(p.MemLevelParallel, next.MemLevelParallel)
+	}
+	return &sum
+}
+
+func calcTMA(pmu *PMUStats, params *UarchParams) *TMAStats {
+	stats := new(TMAStats)
+	stats.pmu = pmu
+	stats.params = params
+	stats.tmaL1 = calcL1(stats.pmu, params)
+	stats.tmaL2 = calcL2(stats.pmu, stats.tmaL1, params)
+	stats.tmaL3 = calcL3(stats.pmu, stats.tmaL2, params)
+	stats.metrics = calcMetrics(stats.pmu)
+	stats.warnings = validateTMA(stats)
+	return stats
+}
+
+// GetStats runs the TMA analysis for every core found in entries and for the
+// aggregate of all cores.
+func GetStats(entries *map[string]Entry, params *UarchParams, profile *Profile) *TMAStats {
+	cores := findCores(entries, profile)
+	total, perCore := getPMU(entries, cores, profile)
+
+	stats := calcTMA(total, params)
+	for i, core := range cores {
+		coreStats := calcTMA(perCore[i], params)
+		coreStats.core = core.label
+		coreStats.mytma = getMyTMA(entries, core, profile)
+		stats.cores = append(stats.cores, coreStats)
+	}
+	stats.mytma = averageTMA(stats.cores)
+	warnings := append(checkMissing(entries, profile, cores), checkUndefined(entries, profile, cores)...)
+	stats.warnings = append(warnings, stats.warnings...)
+	return stats
+}
+
+// GetTimeSeries fills the Interval of every analysed dump. When cumulative is
+// set the counters were not reset between dumps (m5 dumpstats), so each
+// interval is computed from the difference to the previous dump.
+func GetTimeSeries(dumps []Dump, cumulative bool, params *UarchParams) {
+	var tick uint64
+	var prev *PMUStats
+	for i := range dumps {
+		stats := dumps[i].Stats
+		if stats == nil {
+			continue
+		}
+
+		interval := stats
+		if cumulative && prev != nil {
+			interval = calcTMA(stats.pmu.since(prev), params)
+		}
+		prev = stats.pmu
+
+		dumps[i].Interval = &Interval{
+			StartTick: tick,
+			EndTick:   tick + interval.pmu.Simticks,
+			Stats:     interval,
+		}
+		tick = dumps[i].Interval.EndTick
+	}
+}
+
+// SumDumps returns the TMA of the counters of all dumps together, for stats
+// files where gem5 reset the stats after every dump (m5_dumpreset_stats), so
+// that each dump only holds its own interval. The cores are matched by label.
+// Like the intervals of cumulative dumps, the sum has no TMA from gem5 itself.
+func SumDumps(dumps []Dump, params *UarchParams) *TMAStats {
+	var total *PMUStats
+	perCore := make(map[string]*PMUStats)
+	var labels []string
+	for _, dump := range dumps {
+		if dump.Stats == nil {
+			continue
+		}
+		if total == nil {
+			total = dump.Stats.pmu
+		} else {
+			total = total.plus(dump.Stats.pmu)
+		}
+		for _, core := range dump.Stats.cores {
+			if sum, ok := perCore[core.core]; ok {
+				perCore[core.core] = sum.plus(core.pmu)
+			} else {
+				perCore[core.core] = core.pmu
+				labels = append(labels, core.core)
+			}
+		}
+	}
+	if total == nil {
+		return nil
+	}
+
+	stats := calcTMA(total, params)
+	for _, label := range labels {
+		coreStats := calcTMA(perCore[label], params)
+		coreStats.core = label
+		stats.cores = append(stats.cores, coreStats)
+	}
+	return stats
+}
+
+// Core is the label of the analysed core, empty for the aggregate of all cores.
+func (stats *TMAStats) Core() string { return stats.core }
+
+// PMU returns the counters the TMA levels were calculated from.
+func (stats *TMAStats) PMU() *PMUStats { return stats.pmu }
+
+// Params returns the model parameters the TMA levels were calculated with.
+func (stats *TMAStats) Params() *UarchParams { return stats.params }
+
+// GEM5 returns the TMA ratios reported by gem5 itself.
+func (stats *TMAStats) GEM5() *TMAOutStats { return stats.mytma }
+
+func (stats *TMAStats) L1() *L1TMAStats { return stats.tmaL1 }
+
+func (stats *TMAStats) L2() *L2TMAStats { return stats.tmaL2 }
+
+func (stats *TMAStats) L3() *L3TMAStats { return stats.tmaL3 }
+
+// Metrics returns IPC, the MPKIs and the instruction mix.
+func (stats *TMAStats) Metrics() *Metrics { return stats.metrics }
+
+// Cores returns the per core analysis, empty on a per core TMAStats.
+func (stats *TMAStats) Cores() []*TMAStats { return stats.cores }
+
+// Warnings returns the validation warnings of stats and of its cores.
+func (stats *TMAStats) Warnings() []Warning { return stats.allWarnings() }
+
+// Analyse parses a stats file and runs the TMA analysis on every dump, with
+// the stat profile picked by ProfileName (see LoadProfile) from the first dump.
+func Analyse(r io.Reader, interest *Interest, params *UarchParams, ProfileName string) ([]Dump, *Profile, error) {
+	dumps, err := Parselines(r, interest)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(dumps) == 0 {
+		return nil, nil, fmt.Errorf("no interested stats found")
+	}
+	profile, err := LoadProfile(ProfileName, &dumps[0].Entries)
+	if err != nil {
+		return nil, nil, err
+	}
+	for i := range dumps {
+		dumps[i].Stats = GetStats(&dumps[i].Entries, params, profile)
+	}
+	return dumps, profile, nil
+}
@@ -0,0 +1,365 @@
+package gem5stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+// goldenTMA is what the golden files record of an analysed fixture.
+type goldenTMA struct {
+	Profile string      `json:"profile"`
+	L1      *L1TMAStats `json:"tma_l1"`
+	L2      *L2TMAStats `json:"tma_l2"`
+}
+
+// rounded returns a copy of a TMA level with every fraction rounded to 1e-9,
+// so that the golden files do not depend on the last bits of the FPU.
+func rounded[T any](level *T) *T {
+	c := *level
+	v := reflect.ValueOf(&c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		if f := v.Field(i); f.Kind() == reflect.Float64 {
+			f.SetFloat(math.Round(f.Float()*1e9) / 1e9)
+		}
+	}
+	return &c
+}
+
+func analyseFixture(t *testing.T, dir string) ([]Dump, *Profile) {
+	t.Helper()
+	file, err := OpenStats(filepath.Join("testdata", dir, "stats.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	dumps, profile, err := Analyse(file, mustInterest(t, "*\n"), DefaultParams(), "auto")
+	if err != nil {
+		t.Fatalf("Analyse: %v", err)
+	}
+	return dumps, profile
+}
+
+// TestGoldenTMA guards the L1/L2 model: any change of the results for the
+// fixtures has to be reviewed and recorded with go test -update.
+func TestGoldenTMA(t *testing.T) {
+	for _, dir := range []string{"ruby", "classic"} {
+		t.Run(dir, func(t *testing.T) {
+			dumps, profile := analyseFixture(t, dir)
+			stats := dumps[len(dumps)-1].Stats
+
+			got, err := json.MarshalIndent(goldenTMA{profile.Name, rounded(stats.L1()), rounded(stats.L2())}, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join("testdata", dir, "tma.golden.json")
+			if *update {
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("%v (run go test -update to create it)", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("TMA of %s changed, run go test -update if intended\ngot:\n%s\nwant:\n%s", dir, got, want)
+			}
+		})
+	}
+}
+
+func TestTMAInvariants(t *testing.T) {
+	for _, dir := range []string{"ruby", "classic"} {
+		t.Run(dir, func(t *testing.T) {
+			dumps, _ := analyseFixture(t, dir)
+			stats := dumps[len(dumps)-1].Stats
+			l1, l2 := stats.L1(), stats.L2()
+
+			sum := l1.L1_retire + l1.L1_badspec + l1.L1_frontend + l1.L1_backend
+			if sum < 0.999 || sum > 1.001 {
+				t.Errorf("L1 categories sum to %.4f, want 1", sum)
+			}
+			pairs := []struct {
+				parent   string
+				value    float64
+				children float64
+			}{
+				{"Frontend Bound", l1.L1_frontend, l2.L2_fetch_latency + l2.L2_fetch_bandwidth},
+				{"Bad Speculation", l1.L1_badspec, l2.L2_branch_mispredict + l2.L2_machine_clear},
+				{"Backend Bound", l1.L1_backend, l2.L2_memory_bound + l2.L2_core_bound},
+			}
+			for _, p := range pairs {
+				if d := p.value - p.children; d < -1e-9 || d > 1e-9 {
+					t.Errorf("%s is %.4f but its L2 categories sum to %.4f", p.parent, p.value, p.children)
+				}
+			}
+			for _, w := range stats.Warnings() {
+				if w.Kind != WarnMissingStat {
+					t.Errorf("unexpected warning: %s", w)
+				}
+			}
+		})
+	}
+}
+
+func TestCalcL1ZeroCycles(t *testing.T) {
+	stats := calcTMA(&PMUStats{Threads: []ThreadData{{}}}, DefaultParams())
+	found := false
+	for _, w := range stats.Warnings() {
+		if w.Metric == "pmu.cycles" && w.Kind == WarnUndefined {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no warning about zero cycles, got %v", stats.Warnings())
+	}
+	if l1 := stats.L1(); l1.L1_retire != 0 || l1.L1_backend != 0 {
+		t.Errorf("L1 of an empty PMU = %+v, want zeros", *l1)
+	}
+}
+
+func TestCheckUndefined(t *testing.T) {
+	profile, err := LoadProfile("stdlib-ruby", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := map[string]Entry{
+		"board.processor.cores.core.numCycles":   {Name: "board.processor.cores.core.numCycles", Value: 100},
+		"board.processor.cores.core.instsIssued": {Name: "board.processor.cores.core.instsIssued", Undefined: true, Raw: "-nan"},
+	}
+	stats := GetStats(&entries, DefaultParams(), profile)
+
+	found := false
+	for _, w := range stats.Warnings() {
+		if w.Kind == WarnUndefined && w.Metric == "SlotsIssued" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("no warning about the undefined SlotsIssued stat, got %v", stats.Warnings())
+	}
+	if got := stats.PMU().SlotsIssued; got != 0 {
+		t.Errorf("SlotsIssued = %v, want 0 for an undefined stat", got)
+	}
+}
+
+func TestTMATree(t *testing.T) {
+	dumps, _ := analyseFixture(t, "ruby")
+	stats := dumps[len(dumps)-1].Stats
+	roots := TMATree(stats)
+
+	// The counters each category is calculated from, its own first and then
+	// those of the categories it is derived from.
+	l1 := []string{"Cycles", "SlotsRetired", "SlotsIssued", "RecoveryCycles", "FetchStallSlots"}
+	memory := []string{"Cycles", "L1D.Misses", "L2.Misses", "L3.Access", "L3.Misses", "MemLevelParallel",
+		"SlotsRetired", "SlotsIssued", "RecoveryCycles", "FetchStallSlots"}
+	memoryChild := []string{"LSQBlockedByCache", "L1D.Misses", "L2.Misses", "L3.Access", "L3.Misses", "MemQueueStallCount",
+		"Cycles", "MemLevelParallel", "SlotsRetired", "SlotsIssued", "RecoveryCycles", "FetchStallSlots"}
+	core := []string{"Cycles", "SlotsRetired", "SlotsIssued", "RecoveryCycles", "FetchStallSlots",
+		"L1D.Misses", "L2.Misses", "L3.Access", "L3.Misses", "MemLevelParallel"}
+	coreChild := []string{"DivOps", "InstQueueFull", "Cycles", "SlotsRetired", "SlotsIssued", "RecoveryCycles", "FetchStallSlots",
+		"L1D.Misses", "L2.Misses", "L3.Access", "L3.Misses", "MemLevelParallel"}
+	want := map[string][]string{
+		"Retiring":          {"Cycles", "SlotsRetired"},
+		"Bad Speculation":   {"Cycles", "SlotsIssued", "SlotsRetired", "RecoveryCycles"},
+		"Frontend Bound":    {"Cycles", "FetchStallSlots"},
+		"Backend Bound":     l1,
+		"Fetch Latency":     {"Cycles", "FetchCycles"},
+		"Fetch Bandwidth":   {"Cycles", "FetchStallSlots", "FetchCycles"},
+		"Branch Mispred":    {"MispredRetired", "MachineClears", "Cycles", "SlotsIssued", "SlotsRetired", "RecoveryCycles"},
+		"Machine Clears":    {"MispredRetired", "MachineClears", "Cycles", "SlotsIssued", "SlotsRetired", "RecoveryCycles"},
+		"Memory Bound":      memory,
+		"Core Bound":        core,
+		"L1 Bound":          memoryChild,
+		"L2 Bound":          memoryChild,
+		"L3 Bound":          memoryChild,
+		"DRAM Bound":        memoryChild,
+		"Divider":           coreChild,
+		"Ports Utilization": coreChild,
+	}
+
+	if len(roots) != 4 {
+		t.Fatalf("got %d L1 categories, want 4", len(roots))
+	}
+	seen := 0
+	var walk func(nodes []*TMANode)
+	walk = func(nodes []*TMANode) {
+		for _, node := range nodes {
+			seen++
+			var names []string
+			for _, c := range node.Counters {
+				names = append(names, c.Name)
+			}
+			if !reflect.DeepEqual(names, want[node.Name]) {
+				t.Errorf("%s counters = %v, want %v", node.Name, names, want[node.Name])
+			}
+			if len(node.Children) == 0 {
+				continue
+			}
+			sum := 0.0
+			for _, child := range node.Children {
+				if child.Level != node.Level+1 {
+					t.Errorf("%s is on level %d under %s on level %d", child.Name, child.Level, node.Name, node.Level)
+				}
+				sum += child.Value
+			}
+			if d := node.Value - sum; d < -1e-9 || d > 1e-9 {
+				t.Errorf("%s is %.4f but its children sum to %.4f", node.Name, node.Value, sum)
+			}
+			walk(node.Children)
+		}
+	}
+	walk(roots)
+	if seen != len(want) {
+		t.Errorf("got %d categories, want %d", seen, len(want))
+	}
+
+	for _, c := range roots[0].Counters {
+		if c.Name == "Cycles" && c.Value != float64(stats.PMU().Cycles) {
+			t.Errorf("Retiring counter Cycles = %v, want %d", c.Value, stats.PMU().Cycles)
+		}
+	}
+}
+
+// TestStackSegments checks that both bars of the HTML chart cover all the
+// pipeline slots, so that the L2 segments line up under their L1 category.
+func TestStackSegments(t *testing.T) {
+	dumps, _ := analyseFixture(t, "classic")
+	stats := dumps[len(dumps)-1].Stats
+	for _, level := range []int{1, 2} {
+		sum := 0.0
+		for _, seg := range stackSegments(stats, level) {
+			sum += seg.Value
+		}
+		if sum < 0.999 || sum > 1.001 {
+			t.Errorf("level %d segments sum to %.4f, want 1", level, sum)
+		}
+	}
+	if first := stackSegments(stats, 2)[0]; first.Name != "Retiring" {
+		t.Errorf("level 2 bar starts with %s, want Retiring", first.Name)
+	}
+}
+
+// TestCalcL3Split pins how Memory Bound and Core Bound are split, every
+// weight being a stall estimate in cycles.
+func TestCalcL3Split(t *testing.T) {
+	pmu := &PMUStats{
+		LSQBlockedByCache:  10,
+		MemQueueStallCount: 40,
+		DivOps:             5,
+		InstQueueFull:      50,
+	}
+	pmu.L1D.Misses = 100
+	pmu.L2.Misses = 20
+	l2 := &L2TMAStats{L2_memory_bound: 0.4, L2_core_bound: 0.2}
+
+	tests := []struct {
+		name   string
+		params func(p *UarchParams)
+		want   L3TMAStats
+	}{
+		{
+			// L1 3*10, L2 8*80, DRAM 90*20 + 1*40 cycles; divider 20*5, ports 1*50.
+			name:   "defaults",
+			params: func(p *UarchParams) {},
+			want:   L3TMAStats{0.4 * 30 / 2510, 0.4 * 640 / 2510, 0, 0.4 * 1840 / 2510, 0.2 * 100 / 150, 0.2 * 50 / 150},
+		},
+		{
+			name: "penalties",
+			params: func(p *UarchParams) {
+				p.LSQBlockedLat = 10
+				p.MemQueueLat = 5
+				p.IQFullLat = 4
+			},
+			// L1 10*10, DRAM 90*20 + 5*40; ports 4*50.
+			want: L3TMAStats{0.4 * 100 / 2740, 0.4 * 640 / 2740, 0, 0.4 * 2000 / 2740, 0.2 * 100 / 300, 0.2 * 200 / 300},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params := DefaultParams()
+			tt.params(params)
+			got := rounded(calcL3(pmu, l2, params))
+			if want := rounded(&tt.want); *got != *want {
+				t.Errorf("calcL3 = %+v, want %+v", *got, *want)
+			}
+		})
+	}
+}
+
+// TestSumDumps checks that the sum of reset dumps adds up the counters of the
+// whole run and of each core, which leaves the fractions of equal dumps as
+// they are.
+func TestSumDumps(t *testing.T) {
+	dumps, _ := analyseFixture(t, "classic")
+	dump := dumps[len(dumps)-1]
+	sum := SumDumps([]Dump{dump, dump}, DefaultParams())
+
+	if got, want := sum.PMU().Cycles, 2*dump.Stats.PMU().Cycles; got != want {
+		t.Errorf("Cycles = %d, want %d", got, want)
+	}
+	if got, want := rounded(sum.tmaL1), rounded(dump.Stats.tmaL1); *got != *want {
+		t.Errorf("L1 = %+v, want %+v", *got, *want)
+	}
+	if sum.GEM5() != nil {
+		t.Errorf("the sum has a gem5 TMA %+v", *sum.GEM5())
+	}
+	if len(sum.Cores()) != len(dump.Stats.Cores()) {
+		t.Fatalf("got %d cores, want %d", len(sum.Cores()), len(dump.Stats.Cores()))
+	}
+	for i, core := range sum.Cores() {
+		want := dump.Stats.Cores()[i]
+		if core.Core() != want.Core() || core.PMU().SlotsRetired != 2*want.PMU().SlotsRetired {
+			t.Errorf("core %d = %s with %d slots retired, want %s with %d", i, core.Core(), core.PMU().SlotsRetired, want.Core(), 2*want.PMU().SlotsRetired)
+		}
+	}
+}
+
+// TestClassicMemQueueStalls checks that the classic profiles count the L1D
+// accesses blocked for lack of MSHRs, not the cycles, since calcL3 weighs
+// each with mem_queue_latency.
+func TestClassicMemQueueStalls(t *testing.T) {
+	dumps, _ := analyseFixture(t, "classic")
+	if got := dumps[len(dumps)-1].Stats.PMU().MemQueueStallCount; got != 60 {
+		t.Errorf("MemQueueStallCount = %d, want the 60 of blockedCauses::no_mshrs", got)
+	}
+}
+
+// TestCompareUndefinedGEM5 checks that a gem5 TMA category written as nan is
+// not compared as a real 0, which would make it the worst divergence.
+func TestCompareUndefinedGEM5(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "ruby", "stats.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nan := bytes.Replace(data, []byte("L1_Retiring       0.250000"), []byte("L1_Retiring       nan"), 1)
+	dumps, _, err := Analyse(bytes.NewReader(nan), mustInterest(t, "*\n"), DefaultParams(), "auto")
+	if err != nil {
+		t.Fatalf("Analyse: %v", err)
+	}
+
+	comparisons := CompareTMA(dumps[0].Stats)
+	if len(comparisons) != 3 {
+		t.Fatalf("got %d comparisons, want the 3 defined categories: %+v", len(comparisons), comparisons)
+	}
+	for _, c := range comparisons {
+		if c.Category == "Retiring" {
+			t.Errorf("the undefined Retiring of gem5 is compared: %+v", c)
+		}
+	}
+	worst, _, ok := WorstDivergence(dumps)
+	if !ok || worst.Category == "Retiring" {
+		t.Errorf("WorstDivergence = %+v, %v", worst, ok)
+	}
+}
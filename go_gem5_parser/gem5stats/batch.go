@@ -0,0 +1,175 @@
+package gem5stats
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// BatchRun is the analysed whole-run dump of one m5out directory of a sweep.
+type BatchRun struct {
+	Label string
+	Dir   string
+	Dump  Dump
+	Err   error // Why the run could not be analysed, Dump is empty then
+}
+
+// analysedRuns returns the runs that were analysed, leaving out the failed ones.
+func analysedRuns(runs []BatchRun) []BatchRun {
+	var analysed []BatchRun
+	for _, run := range runs {
+		if run.Err == nil {
+			analysed = append(analysed, run)
+		}
+	}
+	return analysed
+}
+
+// batchColumns returns the union of the selected stat names of all runs.
+func batchColumns(runs []BatchRun) []string {
+	names := make(map[string]bool)
+	for _, run := range runs {
+		for name := range run.Dump.Entries {
+			names[name] = true
+		}
+	}
+	columns := make([]string, 0, len(names))
+	for name := range names {
+		columns = append(columns, name)
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// batchTMA returns the L1 and L2 rows of the calculated TMA of a run.
+func batchTMA(run BatchRun) []tmaRow {
+	var rows []tmaRow
+	for _, row := range tmaRows(run.Dump.Stats) {
+		if row.Level <= 2 {
+			rows = append(rows, row)
+		}
+	}
+	return rows
+}
+
+// BatchTable lays the runs out as one row each: the label, the selected stats
+// (empty when a run lacks one) and the TMA L1/L2 metrics. Failed runs are
+// left out.
+func BatchTable(runs []BatchRun) [][]string {
+	runs = analysedRuns(runs)
+	columns := batchColumns(runs)
+	header := append([]string{"run"}, columns...)
+	if len(runs) > 0 {
+		for _, row := range batchTMA(runs[0]) {
+			header = append(header, fmt.Sprintf("L%d %s", row.Level, row.Name))
+		}
+	}
+
+	table := [][]string{header}
+	for _, run := range runs {
+		line := []string{run.Label}
+		for _, name := range columns {
+			if entry, ok := run.Dump.Entries[name]; ok {
+				line = append(line, formatValue(entry))
+			} else {
+				line = append(line, "")
+			}
+		}
+		for _, row := range batchTMA(run) {
+			line = append(line, fmt.Sprintf("%.4f", row.Value))
+		}
+		table = append(table, line)
+	}
+	return table
+}
+
+func writeBatchMarkdown(out io.Writer, table [][]string) error {
+	writer := bufio.NewWriter(out)
+	for i, line := range table {
+		cells := make([]string, len(line))
+		for j, cell := range line {
+			cells[j] = mdEscape(cell)
+		}
+		fmt.Fprintf(writer, "| %s |\n", strings.Join(cells, " | "))
+		if i == 0 {
+			align := make([]string, len(line))
+			align[0] = "---"
+			for j := 1; j < len(align); j++ {
+				align[j] = "---:"
+			}
+			fmt.Fprintf(writer, "| %s |\n", strings.Join(align, " | "))
+		}
+	}
+	return writer.Flush()
+}
+
+func writeBatchCsv(out io.Writer, table [][]string) error {
+	writer := csv.NewWriter(out)
+	writer.UseCRLF = true
+	writer.WriteAll(table)
+	return writer.Error()
+}
+
+// PrintBatch prints the L1 categories of every analysed run, then the runs
+// that failed and why.
+func PrintBatch(w io.Writer, runs []BatchRun) {
+	fmt.Fprintln(w, "==================== TMA Level 1 per Run ====================")
+	fmt.Fprintf(w, "  %-24s  %10s  %10s  %10s  %10s\n", "Run", "Retiring", "BadSpec", "Frontend", "Backend")
+	for _, run := range analysedRuns(runs) {
+		l1 := run.Dump.Stats.tmaL1
+		fmt.Fprintf(w, "  %-24s  %10.4f  %10.4f  %10.4f  %10.4f\n", run.Label, l1.L1_retire, l1.L1_badspec, l1.L1_frontend, l1.L1_backend)
+		for _, warning := range run.Dump.Stats.allWarnings() {
+			fmt.Fprintf(w, "    warning: %s\n", warning)
+		}
+	}
+	fmt.Fprintln(w, "")
+
+	var skipped []BatchRun
+	for _, run := range runs {
+		if run.Err != nil {
+			skipped = append(skipped, run)
+		}
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(w, "Skipped %d of %d runs:\n", len(skipped), len(runs))
+		for _, run := range skipped {
+			fmt.Fprintf(w, "  %s: %v\n", run.Dir, run.Err)
+		}
+		fmt.Fprintln(w, "")
+	}
+}
+
+// BatchWriter writes the BatchTable of the analysed runs of a sweep to w.
+type BatchWriter func(w io.Writer, runs []BatchRun) error
+
+// NewBatchWriter returns the BatchWriter for the given -format value:
+// Markdown, CSV or HTML.
+func NewBatchWriter(format string) (BatchWriter, error) {
+	switch strings.ToLower(format) {
+	case "markdown", "md":
+		return func(w io.Writer, runs []BatchRun) error {
+			return writeBatchMarkdown(w, BatchTable(runs))
+		}, nil
+	case "csv":
+		return func(w io.Writer, runs []BatchRun) error {
+			return writeBatchCsv(w, BatchTable(runs))
+		}, nil
+	case "html", "htm":
+		return func(w io.Writer, runs []BatchRun) error {
+			return writeBatchHtml(w, analysedRuns(runs), BatchTable(runs))
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// WriteBatch writes the BatchTable of the runs to w as Markdown, CSV or HTML.
+func WriteBatch(w io.Writer, runs []BatchRun, format string) error {
+	writer, err := NewBatchWriter(format)
+	if err != nil {
+		return err
+	}
+	return writer(w, runs)
+}
@@ -0,0 +1,104 @@
+package gem5stats
+
+import (
+	"fmt"
+	"io"
+	"math"
+)
+
+// Comparison puts one L1 category calculated by the model next to the value
+// gem5 reports for it.
+type Comparison struct {
+	Core     string  `json:"core"` // Core label, empty for the aggregated view
+	Category string  `json:"category"`
+	Computed float64 `json:"computed"`
+	GEM5     float64 `json:"gem5"`
+	AbsError float64 `json:"abs_error"`
+	RelError float64 `json:"rel_error"` // AbsError relative to the gem5 value, 0 when gem5 reports 0
+}
+
+// compareStats compares a single view; it returns nil when gem5 did not
+// report its own TMA stats (they are not in the interests file). Categories
+// that gem5 wrote as nan or inf are left out, checkUndefined warns about them.
+func compareStats(stats *TMAStats) []Comparison {
+	if stats == nil || stats.mytma == nil || stats.tmaL1 == nil {
+		return nil
+	}
+	t := stats.mytma
+	if t.L1_retire == 0 && t.L1_badspec == 0 && t.L1_frontend == 0 && t.L1_backend == 0 {
+		return nil
+	}
+
+	l1 := stats.tmaL1
+	pairs := []struct {
+		name, field    string
+		computed, gem5 float64
+	}{
+		{"Retiring", "L1_retire", l1.L1_retire, t.L1_retire},
+		{"Bad Speculation", "L1_badspec", l1.L1_badspec, t.L1_badspec},
+		{"Frontend Bound", "L1_frontend", l1.L1_frontend, t.L1_frontend},
+		{"Backend Bound", "L1_backend", l1.L1_backend, t.L1_backend},
+	}
+
+	comparisons := make([]Comparison, 0, len(pairs))
+	for _, p := range pairs {
+		if t.undefined[p.field] {
+			continue
+		}
+		c := Comparison{
+			Core:     stats.core,
+			Category: p.name,
+			Computed: p.computed,
+			GEM5:     p.gem5,
+			AbsError: math.Abs(p.computed - p.gem5),
+		}
+		if p.gem5 != 0 {
+			c.RelError = c.AbsError / math.Abs(p.gem5)
+		}
+		comparisons = append(comparisons, c)
+	}
+	return comparisons
+}
+
+// CompareTMA compares the aggregated view and, on multi-core boards, every core.
+func CompareTMA(stats *TMAStats) []Comparison {
+	comparisons := compareStats(stats)
+	if stats != nil && len(stats.cores) > 1 {
+		for _, core := range stats.cores {
+			comparisons = append(comparisons, compareStats(core)...)
+		}
+	}
+	return comparisons
+}
+
+// WorstDivergence returns the comparison with the largest absolute error over
+// all dumps, and false when there is nothing to compare.
+func WorstDivergence(dumps []Dump) (Comparison, int, bool) {
+	var worst Comparison
+	var worstDump int
+	found := false
+	for _, dump := range dumps {
+		for _, c := range CompareTMA(dump.Stats) {
+			if !found || c.AbsError > worst.AbsError {
+				worst, worstDump, found = c, dump.Index, true
+			}
+		}
+	}
+	return worst, worstDump, found
+}
+
+func PrintComparison(w io.Writer, comparisons []Comparison) {
+	if len(comparisons) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "==================== Calculated vs GEM5 TMA Level 1 ====================")
+	fmt.Fprintf(w, "  %-8s  %-16s  %9s  %9s  %9s  %9s\n", "Core", "Category", "Computed", "GEM5", "Abs Err", "Rel Err")
+	for _, c := range comparisons {
+		core := c.Core
+		if core == "" {
+			core = "all"
+		}
+		fmt.Fprintf(w, "  %-8s  %-16s  %9.4f  %9.4f  %9.4f  %8.1f%%\n", core, c.Category, c.Computed, c.GEM5, c.AbsError, c.RelError*100)
+	}
+	fmt.Fprintln(w, "")
+}
@@ -0,0 +1,167 @@
+package gem5stats
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// StatDiff is the change of one stat between the two runs of a diff.
+type StatDiff struct {
+	Name      string  `json:"name"`
+	Before    float64 `json:"before"`
+	After     float64 `json:"after"`
+	AbsChange float64 `json:"abs_change"`
+	RelChange float64 `json:"rel_change"` // AbsChange relative to Before, 0 when Before is 0
+	Status    string  `json:"status"`     // "changed", "same", "added", "removed" or "undefined"
+}
+
+// TMADiff is the change of one calculated TMA category between the two runs.
+type TMADiff struct {
+	Level    int     `json:"level"`
+	Category string  `json:"category"`
+	Before   float64 `json:"before"`
+	After    float64 `json:"after"`
+	Change   float64 `json:"change"` // In fractions of the pipeline slots
+}
+
+// DiffReport is the result of comparing one dump of two stats files.
+type DiffReport struct {
+	Before string     `json:"before"`
+	After  string     `json:"after"`
+	TMA    []TMADiff  `json:"tma"`
+	Stats  []StatDiff `json:"stats"`
+}
+
+func relChange(before, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return (after - before) / math.Abs(before)
+}
+
+// DiffDumps compares the entries and the calculated TMA of two dumps.
+func DiffDumps(before, after Dump) ([]StatDiff, []TMADiff) {
+	names := make(map[string]bool)
+	for name := range before.Entries {
+		names[name] = true
+	}
+	for name := range after.Entries {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	stats := make([]StatDiff, 0, len(sorted))
+	for _, name := range sorted {
+		b, inBefore := before.Entries[name]
+		a, inAfter := after.Entries[name]
+		d := StatDiff{Name: name, Before: b.Value, After: a.Value}
+		d.AbsChange = a.Value - b.Value
+		d.RelChange = relChange(b.Value, a.Value)
+		switch {
+		case !inBefore:
+			d.Status = "added"
+		case !inAfter:
+			d.Status = "removed"
+		case b.Undefined || a.Undefined:
+			// nan and inf are counted as 0, the change would be meaningless.
+			d.Status = "undefined"
+			d.AbsChange, d.RelChange = 0, 0
+		case d.AbsChange == 0:
+			d.Status = "same"
+		default:
+			d.Status = "changed"
+		}
+		stats = append(stats, d)
+	}
+
+	beforeRows := tmaRows(before.Stats)
+	afterRows := tmaRows(after.Stats)
+	tma := make([]TMADiff, 0, len(beforeRows))
+	for i := range beforeRows {
+		if i >= len(afterRows) {
+			break
+		}
+		tma = append(tma, TMADiff{
+			Level:    beforeRows[i].Level,
+			Category: beforeRows[i].Name,
+			Before:   beforeRows[i].Value,
+			After:    afterRows[i].Value,
+			Change:   afterRows[i].Value - beforeRows[i].Value,
+		})
+	}
+	return stats, tma
+}
+
+func PrintTMADiff(w io.Writer, diffs []TMADiff) {
+	fmt.Fprintln(w, "==================== TMA Change (after - before) ====================")
+	for _, d := range diffs {
+		indent := strings.Repeat("  ", d.Level)
+		fmt.Fprintf(w, "%s%-*s  %8.4f -> %8.4f  (%+8.4f)\n", indent, 24-2*d.Level, d.Category+":", d.Before, d.After, d.Change)
+	}
+	fmt.Fprintln(w, "")
+}
+
+func writeDiffMarkdown(writer *bufio.Writer, report *DiffReport) {
+	fmt.Fprintf(writer, "# %s vs %s\n\n", mdEscape(report.Before), mdEscape(report.After))
+
+	fmt.Fprintln(writer, "## TMA Change")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "| Level | Category | Before | After | Change |")
+	fmt.Fprintln(writer, "| ---: | --- | ---: | ---: | ---: |")
+	for _, d := range report.TMA {
+		fmt.Fprintf(writer, "| %d | %s | %.4f | %.4f | %+.4f |\n", d.Level, d.Category, d.Before, d.After, d.Change)
+	}
+	fmt.Fprintln(writer)
+
+	fmt.Fprintln(writer, "## Stat Change")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "| Name | Before | After | Change | Relative | Status |")
+	fmt.Fprintln(writer, "| --- | ---: | ---: | ---: | ---: | --- |")
+	for _, d := range report.Stats {
+		fmt.Fprintf(writer, "| %s | %s | %s | %s | %+.2f%% | %s |\n", mdEscape(d.Name),
+			formatFloat(d.Before), formatFloat(d.After), formatFloat(d.AbsChange), d.RelChange*100, d.Status)
+	}
+}
+
+func writeDiffCsv(out io.Writer, report *DiffReport) error {
+	writer := csv.NewWriter(out)
+	writer.UseCRLF = true
+
+	writer.Write([]string{"section", "name", "before", "after", "abs_change", "rel_change", "status"})
+	for _, d := range report.TMA {
+		writer.Write([]string{fmt.Sprintf("tma_l%d", d.Level), d.Category, formatFloat(d.Before), formatFloat(d.After), formatFloat(d.Change), "", ""})
+	}
+	for _, d := range report.Stats {
+		writer.Write([]string{"stat", d.Name, formatFloat(d.Before), formatFloat(d.After), formatFloat(d.AbsChange), formatFloat(d.RelChange), d.Status})
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteDiff writes the report to w as Markdown, CSV or JSON.
+func WriteDiff(w io.Writer, report *DiffReport, format string) error {
+	switch strings.ToLower(format) {
+	case "markdown", "md":
+		writer := bufio.NewWriter(w)
+		writeDiffMarkdown(writer, report)
+		return writer.Flush()
+	case "csv":
+		return writeDiffCsv(w, report)
+	case "json":
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(report)
+	}
+	return fmt.Errorf("unknown output format %q", format)
+}
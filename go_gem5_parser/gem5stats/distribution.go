@@ -0,0 +1,254 @@
+package gem5stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Bucket is one range of a gem5 histogram, e.g. loadToUse::10-19.
+type Bucket struct {
+	Low                  float64 `json:"low"`
+	High                 float64 `json:"high"`
+	Count                float64 `json:"count"`
+	Percentage           float64 `json:"percentage"`
+	CumulativePercentage float64 `json:"cumulative_percentage"`
+
+	Undefined     bool   `json:"undefined"`                // gem5 wrote nan or inf for the count or a percentage
+	Raw           string `json:"raw,omitempty"`            // The count as written by gem5 when undefined
+	RawPercentage string `json:"raw_percentage,omitempty"` // The percentages as written by gem5 when undefined, without %
+	RawCumulative string `json:"raw_cumulative_percentage,omitempty"`
+}
+
+// Distribution groups the "::" lines that gem5 writes for a distribution or
+// histogram stat into one value.
+type Distribution struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Samples     float64  `json:"samples"`
+	Mean        float64  `json:"mean"`
+	Stdev       float64  `json:"stdev"`
+	Underflows  float64  `json:"underflows"`
+	Overflows   float64  `json:"overflows"`
+	MinValue    float64  `json:"min_value"`
+	MaxValue    float64  `json:"max_value"`
+	Total       float64  `json:"total"`
+	Buckets     []Bucket `json:"buckets"`
+
+	// Summary sub-keys that gem5 wrote as nan or inf (e.g. the mean of an
+	// empty histogram), as written. Their field is 0.
+	Undefined map[string]string `json:"undefined,omitempty"`
+}
+
+// summary formats a summary field of d, or what gem5 wrote when it is undefined.
+func (d Distribution) summary(key string, v float64, format string) string {
+	if raw, ok := d.Undefined[key]; ok {
+		return raw
+	}
+	if format == "" {
+		return formatFloat(v)
+	}
+	return fmt.Sprintf(format, v)
+}
+
+// distributionFields are the summary sub-keys that only distributions have.
+// Any of them turns a "::" group into a Distribution.
+var distributionFields = map[string]func(d *Distribution) *float64{
+	"samples":    func(d *Distribution) *float64 { return &d.Samples },
+	"mean":       func(d *Distribution) *float64 { return &d.Mean },
+	"stdev":      func(d *Distribution) *float64 { return &d.Stdev },
+	"underflows": func(d *Distribution) *float64 { return &d.Underflows },
+	"overflows":  func(d *Distribution) *float64 { return &d.Overflows },
+	"min_value":  func(d *Distribution) *float64 { return &d.MinValue },
+	"max_value":  func(d *Distribution) *float64 { return &d.MaxValue },
+}
+
+// parseBucket reads a bucket sub-key, either a range "10-19" (negative bounds
+// are written "-10--1") or a single value "5" for buckets of size one.
+func parseBucket(key string) (float64, float64, bool) {
+	if v, err := strconv.ParseFloat(key, 64); err == nil {
+		return v, v, true
+	}
+	for i := 1; i < len(key); i++ {
+		if key[i] != '-' {
+			continue
+		}
+		low, err1 := strconv.ParseFloat(key[:i], 64)
+		high, err2 := strconv.ParseFloat(key[i+1:], 64)
+		if err1 == nil && err2 == nil {
+			return low, high, true
+		}
+	}
+	return 0, 0, false
+}
+
+// splitSubKey splits "parent::key" at the last "::".
+func splitSubKey(name string) (string, string, bool) {
+	i := strings.LastIndex(name, "::")
+	if i == -1 {
+		return "", "", false
+	}
+	return name[:i], name[i+2:], true
+}
+
+// GroupDistributions collects the distribution stats among the entries,
+// ordered by name. Groups without a summary sub-key are vectors and skipped.
+func GroupDistributions(entries map[string]Entry) []Distribution {
+	groups := make(map[string][]Entry)
+	for name, entry := range entries {
+		if parent, _, ok := splitSubKey(name); ok {
+			groups[parent] = append(groups[parent], entry)
+		}
+	}
+
+	var dists []Distribution
+	for parent, group := range groups {
+		dist := Distribution{Name: parent}
+		isDist := false
+		for _, entry := range group {
+			_, key, _ := splitSubKey(entry.Name)
+			if dist.Description == "" {
+				dist.Description = entry.Description
+			}
+			if field, ok := distributionFields[key]; ok || key == "total" {
+				if ok {
+					*field(&dist) = entry.Value
+					isDist = true
+				} else {
+					dist.Total = entry.Value
+				}
+				if entry.Undefined {
+					if dist.Undefined == nil {
+						dist.Undefined = make(map[string]string)
+					}
+					dist.Undefined[key] = entry.Raw
+				}
+			} else if low, high, ok := parseBucket(key); ok {
+				dist.Buckets = append(dist.Buckets, Bucket{
+					Low:                  low,
+					High:                 high,
+					Count:                entry.Value,
+					Percentage:           entry.Percentage1,
+					CumulativePercentage: entry.Percentage2,
+					Undefined:            entry.Undefined || entry.UndefinedPercentage,
+					Raw:                  entry.Raw,
+					RawPercentage:        entry.RawPercentage1,
+					RawCumulative:        entry.RawPercentage2,
+				})
+			}
+		}
+		if !isDist {
+			continue
+		}
+		sort.Slice(dist.Buckets, func(i, j int) bool {
+			return dist.Buckets[i].Low < dist.Buckets[j].Low
+		})
+		dists = append(dists, dist)
+	}
+
+	sort.Slice(dists, func(i, j int) bool {
+		return dists[i].Name < dists[j].Name
+	})
+	return dists
+}
+
+// bucketLabel formats a bucket the way gem5 names it.
+func bucketLabel(b Bucket) string {
+	if b.Low == b.High {
+		return formatFloat(b.Low)
+	}
+	return formatFloat(b.Low) + "-" + formatFloat(b.High)
+}
+
+// histogramBar draws a percentage as a bar of at most width characters.
+func histogramBar(percentage float64, width int) string {
+	n := int(percentage/100*float64(width) + 0.5)
+	n = max(0, min(n, width))
+	return strings.Repeat("#", n)
+}
+
+// distRow is one line of a rendered histogram, the numbers formatted for
+// display.
+type distRow struct {
+	Label      string
+	Count      string
+	Percentage string
+	Cumulative string
+	Share      float64 // Percentage as a number, for the histogram bar
+}
+
+// distributionRows returns the buckets of a distribution framed by the
+// underflow and overflow counts.
+func distributionRows(dist Distribution) []distRow {
+	share := func(count float64) float64 {
+		if dist.Samples <= 0 {
+			return 0
+		}
+		return count / dist.Samples * 100
+	}
+
+	pct := func(v float64) string { return fmt.Sprintf("%.2f", v) }
+
+	var rows []distRow
+	var cumulative float64
+	if dist.Underflows > 0 {
+		cumulative = share(dist.Underflows)
+		rows = append(rows, distRow{"underflows", formatFloat(dist.Underflows), pct(cumulative), pct(cumulative), cumulative})
+	}
+	for _, b := range dist.Buckets {
+		cumulative = b.CumulativePercentage
+		rows = append(rows, distRow{bucketLabel(b), rawOr(b.Raw, formatFloat(b.Count)),
+			rawOr(b.RawPercentage, pct(b.Percentage)), rawOr(b.RawCumulative, pct(cumulative)), b.Percentage})
+	}
+	if dist.Overflows > 0 {
+		rows = append(rows, distRow{"overflows", formatFloat(dist.Overflows), pct(share(dist.Overflows)),
+			pct(cumulative + share(dist.Overflows)), share(dist.Overflows)})
+	}
+	return rows
+}
+
+func writeMdDistributions(writer *bufio.Writer, dists []Distribution) {
+	fmt.Fprintln(writer, "## Distributions")
+	fmt.Fprintln(writer)
+	for _, dist := range dists {
+		fmt.Fprintf(writer, "### %s\n\n", mdEscape(dist.Name))
+		if dist.Description != "" {
+			fmt.Fprintf(writer, "%s\n\n", mdEscape(dist.Description))
+		}
+		fmt.Fprintf(writer, "Samples %s, mean %s, stdev %s, min %s, max %s\n\n",
+			dist.summary("samples", dist.Samples, ""), dist.summary("mean", dist.Mean, "%.4f"), dist.summary("stdev", dist.Stdev, "%.4f"),
+			dist.summary("min_value", dist.MinValue, ""), dist.summary("max_value", dist.MaxValue, ""))
+
+		rows := distributionRows(dist)
+		if len(rows) == 0 {
+			continue
+		}
+		fmt.Fprintln(writer, "| Bucket | Count | Percentage | Cumulative | Histogram |")
+		fmt.Fprintln(writer, "| --- | ---: | ---: | ---: | --- |")
+		for _, row := range rows {
+			fmt.Fprintf(writer, "| %s | %s | %s%% | %s%% | `%s` |\n",
+				row.Label, row.Count, row.Percentage, row.Cumulative, histogramBar(row.Share, 40))
+		}
+		fmt.Fprintln(writer)
+	}
+}
+
+func PrintDistributions(w io.Writer, dists []Distribution) {
+	if len(dists) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "==================== Distributions ====================")
+	for _, dist := range dists {
+		fmt.Fprintf(w, "  %s\n", dist.Name)
+		fmt.Fprintf(w, "    samples %s  mean %s  stdev %s  min %s  max %s\n",
+			dist.summary("samples", dist.Samples, ""), dist.summary("mean", dist.Mean, "%.4f"), dist.summary("stdev", dist.Stdev, "%.4f"),
+			dist.summary("min_value", dist.MinValue, ""), dist.summary("max_value", dist.MaxValue, ""))
+		for _, row := range distributionRows(dist) {
+			fmt.Fprintf(w, "    %-12s %12s %7s%%  %s\n", row.Label, row.Count, row.Percentage, histogramBar(row.Share, 40))
+		}
+	}
+	fmt.Fprintln(w, "")
+}
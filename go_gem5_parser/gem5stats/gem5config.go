@@ -0,0 +1,254 @@
+package gem5stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// gem5Object is one SimObject of config.json, decoded without a schema.
+type gem5Object map[string]any
+
+// number reads a numeric parameter. Vector parameters such as clock are
+// written as lists, in which case the first element is used.
+func (o gem5Object) number(key string) (float64, bool) {
+	switch v := o[key].(type) {
+	case float64:
+		return v, true
+	case []any:
+		if len(v) > 0 {
+			f, ok := v[0].(float64)
+			return f, ok
+		}
+	}
+	return 0, false
+}
+
+func (o gem5Object) path() string {
+	p, _ := o["path"].(string)
+	return p
+}
+
+// collectObjects indexes every SimObject of the tree by its path.
+func collectObjects(node any, objects map[string]gem5Object, order *[]gem5Object) {
+	switch v := node.(type) {
+	case map[string]any:
+		obj := gem5Object(v)
+		if p := obj.path(); p != "" {
+			objects[p] = obj
+			*order = append(*order, obj)
+		}
+		for _, child := range v {
+			collectObjects(child, objects, order)
+		}
+	case []any:
+		for _, child := range v {
+			collectObjects(child, objects, order)
+		}
+	}
+}
+
+// peers returns the ports a port parameter of obj is connected to. A port is
+// written as {"peer": ...}, with a list for vector ports.
+func (o gem5Object) peers(port string) []string {
+	p, _ := o[port].(map[string]any)
+	switch v := p["peer"].(type) {
+	case string:
+		return []string{v}
+	case []any:
+		var peers []string
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				peers = append(peers, s)
+			}
+		}
+		return peers
+	}
+	return nil
+}
+
+// cacheLevel tells which level of the hierarchy a cache belongs to, or 0 for
+// the instruction cache and anything that is not a data/unified cache. The L1
+// caches are known by their name (l1icaches, icache, L1Dcache, dcache) or by
+// being connected to the icache_port or dcache_port of a CPU, the classic SE
+// configs call them just icache and dcache.
+func cacheLevel(obj gem5Object) int {
+	p := strings.ToLower(obj.path())
+	name := p[strings.LastIndex(p, ".")+1:]
+	connected := func(port string) bool {
+		for _, peer := range obj.peers("cpu_side") {
+			if strings.HasSuffix(peer, "."+port) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case strings.Contains(p, "l1i") || strings.Contains(name, "icache") || connected("icache_port"):
+		return 0
+	case strings.Contains(p, "l1d") || strings.Contains(name, "dcache") || connected("dcache_port"):
+		return 1
+	case strings.Contains(p, "l3"):
+		return 3
+	case strings.Contains(p, "l2"):
+		return 2
+	}
+	return 0
+}
+
+// cacheLatency is the hit latency of a cache object in cycles, for both the
+// classic Cache and the Ruby CacheMemory parameter names.
+func cacheLatency(obj gem5Object) (float64, bool) {
+	for _, keys := range [][2]string{{"tag_latency", "data_latency"}, {"tagAccessLatency", "dataAccessLatency"}} {
+		tag, ok1 := obj.number(keys[0])
+		data, ok2 := obj.number(keys[1])
+		if ok1 && ok2 {
+			return tag + data, true
+		}
+	}
+	return 0, false
+}
+
+// ticksPerNs assumes the default gem5 resolution of 1 tick = 1ps.
+const ticksPerNs = 1000.0
+
+// readGem5Config decodes a config.json document.
+func readGem5Config(r io.Reader, source string) (any, error) {
+	var root any
+	if err := json.NewDecoder(r).Decode(&root); err != nil {
+		return nil, fmt.Errorf("invalid gem5 config %s: %w", source, err)
+	}
+	return root, nil
+}
+
+// LoadGem5Config derives the model parameters from the config.json that gem5
+// writes next to stats.txt and overlays them on params. Parameters that are
+// not found in the document keep their current value. source names the
+// document in UarchParams.Source and in errors.
+func LoadGem5Config(r io.Reader, source string, params *UarchParams) (*UarchParams, error) {
+	root, err := readGem5Config(r, source)
+	if err != nil {
+		return nil, err
+	}
+
+	objects := make(map[string]gem5Object)
+	var order []gem5Object
+	collectObjects(root, objects, &order)
+
+	loaded := *params
+	loaded.Source = source
+
+	var cpu gem5Object
+	var dram, memCtrl gem5Object
+	cacheFound := make(map[int]bool)
+	for _, obj := range order {
+		if _, ok := obj.number("dispatchWidth"); ok && cpu == nil {
+			cpu = obj
+		}
+		if _, ok := obj.number("tCL"); ok && dram == nil {
+			dram = obj
+		}
+		if _, ok := obj.number("static_frontend_latency"); ok && memCtrl == nil {
+			memCtrl = obj
+		}
+		if class, _ := obj["opClass"].(string); class == "IntDiv" {
+			if lat, ok := obj.number("opLat"); ok && lat > 0 {
+				loaded.DivLat = lat
+			}
+		}
+		if lat, ok := cacheLatency(obj); ok {
+			// Private caches repeat per core, the first one stands for all.
+			level := cacheLevel(obj)
+			if level == 0 || cacheFound[level] {
+				continue
+			}
+			cacheFound[level] = true
+			switch level {
+			case 1:
+				loaded.L1Lat = lat
+			case 2:
+				loaded.L2Lat = lat
+			case 3:
+				loaded.L3Lat = lat
+			}
+		}
+	}
+
+	if cpu != nil {
+		if v, ok := cpu.number("issueWidth"); ok && v > 0 {
+			loaded.IssueWidth = uint64(v)
+		}
+		if v, ok := cpu.number("dispatchWidth"); ok && v > 0 {
+			loaded.DispatchWidth = uint64(v)
+		}
+
+		// clk_domain is a reference to the domain object when it is not a child.
+		var domain gem5Object
+		switch v := cpu["clk_domain"].(type) {
+		case string:
+			domain = objects[v]
+		case map[string]any:
+			domain = v
+		}
+		if period, ok := domain.number("clock"); ok && period > 0 {
+			loaded.ClockGHz = ticksPerNs / period
+		}
+	}
+
+	// Memory latency of a closed row: activate, CAS and the burst, plus the
+	// static pipeline latencies of the controller. On-chip lookups are not
+	// included since the model charges them to the cache levels.
+	if dram != nil {
+		var ticks float64
+		for _, key := range []string{"tRCD", "tCL", "tBURST"} {
+			v, _ := dram.number(key)
+			ticks += v
+		}
+		if memCtrl != nil {
+			for _, key := range []string{"static_frontend_latency", "static_backend_latency"} {
+				v, _ := memCtrl.number(key)
+				ticks += v
+			}
+		}
+		if ticks > 0 {
+			loaded.MemLatNs = ticks / ticksPerNs
+		}
+	}
+
+	return &loaded, nil
+}
+
+// Gem5ConfigValue looks up one parameter of config.json by its dotted path,
+// e.g. board.cache_hierarchy.l2-cache-0.size. Lists are joined with commas.
+func Gem5ConfigValue(r io.Reader, key string) (string, bool, error) {
+	root, err := readGem5Config(r, "config.json")
+	if err != nil {
+		return "", false, err
+	}
+
+	objects := make(map[string]gem5Object)
+	var order []gem5Object
+	collectObjects(root, objects, &order)
+
+	dot := strings.LastIndex(key, ".")
+	if dot == -1 {
+		return "", false, nil
+	}
+	obj, ok := objects[key[:dot]]
+	if !ok {
+		return "", false, nil
+	}
+	switch v := obj[key[dot+1:]].(type) {
+	case nil, map[string]any:
+		return "", false, nil
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = fmt.Sprint(item)
+		}
+		return strings.Join(parts, ","), true, nil
+	default:
+		return fmt.Sprint(v), true, nil
+	}
+}
@@ -0,0 +1,344 @@
+package gem5stats
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"math"
+	"strings"
+)
+
+// HtmlWriter emits a self-contained HTML page with the TMA levels drawn as
+// inline SVG stacked bars, the cache miss rates and the selected entries.
+type HtmlWriter struct{}
+
+// tmaColors fills the bar segments. The L2 categories are shades of the L1
+// category they break down.
+var tmaColors = map[string]string{
+	"Retiring":        "#43a047",
+	"Bad Speculation": "#e53935",
+	"Branch Mispred":  "#c62828",
+	"Machine Clears":  "#ef9a9a",
+	"Frontend Bound":  "#5c6bc0",
+	"Fetch Latency":   "#303f9f",
+	"Fetch Bandwidth": "#9fa8da",
+	"Backend Bound":   "#fb8c00",
+	"Memory Bound":    "#e65100",
+	"Core Bound":      "#ffcc80",
+}
+
+// stackBar is one bar of a stacked bar chart, its segments add up to the
+// pipeline slots.
+type stackBar struct {
+	Label    string
+	Segments []tmaRow
+}
+
+// stackSegments returns the L1 categories of stats in print order, or with
+// level 2 their breakdown in the same order so that the bars line up. L1
+// categories without a breakdown (Retiring) stand for themselves.
+func stackSegments(stats *TMAStats, level int) []tmaRow {
+	rows := tmaRows(stats)
+	var segments []tmaRow
+	for _, row := range rows {
+		if row.Level != 1 {
+			continue
+		}
+		found := false
+		if level == 2 {
+			for _, child := range rows {
+				if child.Level == 2 && child.Parent == row.Name {
+					segments = append(segments, child)
+					found = true
+				}
+			}
+		}
+		if !found {
+			segments = append(segments, row)
+		}
+	}
+	return segments
+}
+
+const (
+	svgLabelWidth = 160
+	svgPlotWidth  = 640
+	svgBarHeight  = 24
+	svgBarGap     = 10
+)
+
+// writeSvgStack draws one horizontal bar per entry of bars, the full width
+// being all pipeline slots. Negative fractions are left out.
+func writeSvgStack(writer *bufio.Writer, bars []stackBar) {
+	width := svgLabelWidth + svgPlotWidth + 20
+	height := len(bars)*(svgBarHeight+svgBarGap) + 24
+	fmt.Fprintf(writer, "<svg xmlns=\"http://www.w3.org/2000/svg\" width=\"%d\" height=\"%d\" viewBox=\"0 0 %d %d\" role=\"img\">\n", width, height, width, height)
+	for i, bar := range bars {
+		y := i * (svgBarHeight + svgBarGap)
+		fmt.Fprintf(writer, "<text x=\"%d\" y=\"%d\" text-anchor=\"end\" dominant-baseline=\"middle\">%s</text>\n",
+			svgLabelWidth-8, y+svgBarHeight/2, html.EscapeString(bar.Label))
+		x := 0.0
+		for _, seg := range bar.Segments {
+			w := math.Min(math.Max(0, seg.Value), 1-x/svgPlotWidth) * svgPlotWidth
+			if w <= 0 {
+				continue
+			}
+			color, ok := tmaColors[seg.Name]
+			if !ok {
+				color = "#9e9e9e"
+			}
+			fmt.Fprintf(writer, "<rect x=\"%.1f\" y=\"%d\" width=\"%.1f\" height=\"%d\" fill=\"%s\"><title>%s: %.4f (%.1f%% of slots)</title></rect>\n",
+				svgLabelWidth+x, y, w, svgBarHeight, color, html.EscapeString(seg.Name), seg.Value, seg.Value*100)
+			if w >= 40 {
+				fmt.Fprintf(writer, "<text x=\"%.1f\" y=\"%d\" text-anchor=\"middle\" dominant-baseline=\"middle\" fill=\"#fff\">%.0f%%</text>\n",
+					svgLabelWidth+x+w/2, y+svgBarHeight/2, seg.Value*100)
+			}
+			x += w
+		}
+	}
+
+	// Axis in percent of the pipeline slots.
+	axis := len(bars) * (svgBarHeight + svgBarGap)
+	fmt.Fprintf(writer, "<line x1=\"%d\" y1=\"%d\" x2=\"%d\" y2=\"%d\" stroke=\"#757575\"/>\n", svgLabelWidth, axis, svgLabelWidth+svgPlotWidth, axis)
+	for pct := 0; pct <= 100; pct += 25 {
+		x := svgLabelWidth + pct*svgPlotWidth/100
+		fmt.Fprintf(writer, "<text x=\"%d\" y=\"%d\" text-anchor=\"middle\">%d%%</text>\n", x, axis+16, pct)
+	}
+	fmt.Fprintln(writer, "</svg>")
+}
+
+// writeHtmlLegend lists the categories drawn in bars, in order of appearance.
+func writeHtmlLegend(writer *bufio.Writer, bars []stackBar) {
+	seen := make(map[string]bool)
+	fmt.Fprintln(writer, "<p class=\"legend\">")
+	for _, bar := range bars {
+		for _, seg := range bar.Segments {
+			if seen[seg.Name] {
+				continue
+			}
+			seen[seg.Name] = true
+			fmt.Fprintf(writer, "<span><i style=\"background:%s\"></i>%s</span>\n", tmaColors[seg.Name], html.EscapeString(seg.Name))
+		}
+	}
+	fmt.Fprintln(writer, "</p>")
+}
+
+// writeHtmlChart draws a chart with its legend.
+func writeHtmlChart(writer *bufio.Writer, bars []stackBar) {
+	writeSvgStack(writer, bars)
+	writeHtmlLegend(writer, bars)
+}
+
+// writeHtmlTable writes a table, align holds one l or r per column.
+func writeHtmlTable(writer *bufio.Writer, align string, header []string, rows [][]string) {
+	cell := func(tag string, i int, s string) {
+		class := ""
+		if i < len(align) && align[i] == 'r' {
+			class = " class=\"r\""
+		}
+		fmt.Fprintf(writer, "<%s%s>%s</%s>", tag, class, html.EscapeString(s), tag)
+	}
+	fmt.Fprintln(writer, "<table>")
+	fmt.Fprint(writer, "<tr>")
+	for i, s := range header {
+		cell("th", i, s)
+	}
+	fmt.Fprintln(writer, "</tr>")
+	for _, row := range rows {
+		fmt.Fprint(writer, "<tr>")
+		for i, s := range row {
+			cell("td", i, s)
+		}
+		fmt.Fprintln(writer, "</tr>")
+	}
+	fmt.Fprintln(writer, "</table>")
+}
+
+func writeHtmlHead(writer *bufio.Writer, title string) {
+	fmt.Fprintln(writer, "<!DOCTYPE html>")
+	fmt.Fprintln(writer, "<html lang=\"en\">")
+	fmt.Fprintln(writer, "<head>")
+	fmt.Fprintln(writer, "<meta charset=\"utf-8\">")
+	fmt.Fprintf(writer, "<title>%s</title>\n", html.EscapeString(title))
+	fmt.Fprintln(writer, `<style>
+body { font-family: sans-serif; margin: 2em; color: #212121; }
+table { border-collapse: collapse; margin-bottom: 1.5em; }
+th, td { border: 1px solid #ccc; padding: 0.2em 0.6em; text-align: left; }
+th { background: #f5f5f5; }
+.r { text-align: right; }
+.legend span { display: inline-block; margin-right: 1.2em; }
+.legend i { display: inline-block; width: 0.9em; height: 0.9em; margin-right: 0.3em; vertical-align: middle; }
+svg text { font-size: 12px; }
+</style>`)
+	fmt.Fprintln(writer, "</head>")
+	fmt.Fprintln(writer, "<body>")
+	fmt.Fprintf(writer, "<h1>%s</h1>\n", html.EscapeString(title))
+}
+
+func writeHtmlTail(writer *bufio.Writer) {
+	fmt.Fprintln(writer, "</body>")
+	fmt.Fprintln(writer, "</html>")
+}
+
+// cacheRows lists access, hits, misses and miss rate of every cache level
+// that saw any traffic.
+func cacheRows(pmu *PMUStats) [][]string {
+	if pmu == nil {
+		return nil
+	}
+	caches := []struct {
+		name  string
+		stats CacheStats
+	}{{"L1D", pmu.L1D}, {"L1I", pmu.L1I}, {"L2", pmu.L2}, {"L3", pmu.L3}}
+
+	var rows [][]string
+	for _, c := range caches {
+		if c.stats.Access == 0 && c.stats.Misses == 0 {
+			continue
+		}
+		rows = append(rows, []string{c.name, fmt.Sprint(c.stats.Access), fmt.Sprint(c.stats.Hits),
+			fmt.Sprint(c.stats.Misses), fmt.Sprintf("%.2f%%", c.stats.MissRate*100)})
+	}
+	return rows
+}
+
+func (w HtmlWriter) Write(dumps []Dump, out io.Writer) error {
+	writer := bufio.NewWriter(out)
+	writeHtmlHead(writer, "gem5 TMA Report")
+
+	if params := paramRows(dumpParams(dumps)); params != nil {
+		rows := make([][]string, len(params))
+		for i, param := range params {
+			rows[i] = []string{param[0], param[1]}
+		}
+		fmt.Fprintln(writer, "<h2>TMA Model Parameters</h2>")
+		writeHtmlTable(writer, "lr", []string{"Parameter", "Value"}, rows)
+	}
+
+	if len(dumps) > 1 {
+		var bars []stackBar
+		for _, dump := range dumps {
+			if dump.Interval != nil {
+				bars = append(bars, stackBar{fmt.Sprintf("Dump %d", dump.Index), stackSegments(dump.Interval.Stats, 1)})
+			}
+		}
+		if len(bars) > 0 {
+			fmt.Fprintln(writer, "<h2>Time Series</h2>")
+			writeHtmlChart(writer, bars)
+		}
+	}
+
+	for _, dump := range dumps {
+		if len(dumps) > 1 {
+			fmt.Fprintf(writer, "<h2>Dump %d</h2>\n", dump.Index)
+		}
+		writeHtmlDump(writer, dump)
+	}
+
+	writeHtmlTail(writer)
+	return writer.Flush()
+}
+
+func writeHtmlDump(writer *bufio.Writer, dump Dump) {
+	stats := dump.Stats
+	if stats != nil && stats.metrics != nil {
+		var rows [][]string
+		for _, row := range metricRows(stats.metrics) {
+			rows = append(rows, []string{row[0], row[1]})
+		}
+		fmt.Fprintln(writer, "<h3>Performance Summary</h3>")
+		writeHtmlTable(writer, "lr", []string{"Metric", "Value"}, rows)
+	}
+
+	if rows := tmaRows(stats); rows != nil {
+		fmt.Fprintln(writer, "<h3>Top-Down Breakdown</h3>")
+		writeHtmlChart(writer, []stackBar{
+			{"Level 1", stackSegments(stats, 1)},
+			{"Level 2", stackSegments(stats, 2)},
+		})
+
+		table := make([][]string, len(rows))
+		for i, row := range rows {
+			share := row.Value * 100
+			if row.Level > 1 {
+				share = parentShare(rows, row)
+			}
+			table[i] = []string{fmt.Sprint(row.Level), row.Name, row.Parent, fmt.Sprintf("%.4f", row.Value), fmt.Sprintf("%.1f%%", share)}
+		}
+		writeHtmlTable(writer, "rllrr", []string{"Level", "Category", "Parent", "Value", "Share of Parent"}, table)
+	}
+
+	if stats != nil && len(stats.cores) > 1 {
+		var bars []stackBar
+		for _, core := range stats.cores {
+			bars = append(bars, stackBar{core.core, stackSegments(core, 1)})
+		}
+		fmt.Fprintln(writer, "<h3>Top-Down per Core</h3>")
+		writeHtmlChart(writer, bars)
+	}
+
+	if stats != nil {
+		if rows := cacheRows(stats.pmu); len(rows) > 0 {
+			fmt.Fprintln(writer, "<h3>Cache Miss Rates</h3>")
+			writeHtmlTable(writer, "lrrrr", []string{"Cache", "Accesses", "Hits", "Misses", "Miss Rate"}, rows)
+		}
+	}
+
+	if warnings := stats.allWarnings(); len(warnings) > 0 {
+		fmt.Fprintln(writer, "<h3>Warnings</h3>")
+		fmt.Fprintln(writer, "<ul>")
+		for _, w := range warnings {
+			fmt.Fprintf(writer, "<li>%s</li>\n", html.EscapeString(w.String()))
+		}
+		fmt.Fprintln(writer, "</ul>")
+	}
+
+	entries := sortedEntries(dump.Entries)
+	rows := make([][]string, len(entries))
+	for i, entry := range entries {
+		p1, p2 := formatPercentages(entry)
+		if entry.HasPercentage {
+			p1, p2 = p1+"%", p2+"%"
+		}
+		rows[i] = []string{entry.Name, formatValue(entry), p1, p2, entry.Description}
+	}
+	fmt.Fprintln(writer, "<h3>Selected Statistics</h3>")
+	writeHtmlTable(writer, "lrrrl", []string{"Name", "Value", "Percentage", "Cumulative", "Description"}, rows)
+}
+
+// writeBatchHtml draws the L1 and L2 breakdown of every run of a sweep as one
+// bar each, followed by the BatchTable.
+func writeBatchHtml(out io.Writer, runs []BatchRun, table [][]string) error {
+	writer := bufio.NewWriter(out)
+	writeHtmlHead(writer, "gem5 TMA Sweep")
+
+	var l1, l2 []stackBar
+	for _, run := range runs {
+		l1 = append(l1, stackBar{run.Label, stackSegments(run.Dump.Stats, 1)})
+		l2 = append(l2, stackBar{run.Label, stackSegments(run.Dump.Stats, 2)})
+	}
+	fmt.Fprintln(writer, "<h2>TMA Level 1 per Run</h2>")
+	writeHtmlChart(writer, l1)
+	fmt.Fprintln(writer, "<h2>TMA Level 2 per Run</h2>")
+	writeHtmlChart(writer, l2)
+
+	var rows [][]string
+	for _, run := range runs {
+		for _, row := range cacheRows(run.Dump.Stats.pmu) {
+			rows = append(rows, append([]string{run.Label}, row...))
+		}
+	}
+	if len(rows) > 0 {
+		fmt.Fprintln(writer, "<h2>Cache Miss Rates</h2>")
+		writeHtmlTable(writer, "llrrrr", []string{"Run", "Cache", "Accesses", "Hits", "Misses", "Miss Rate"}, rows)
+	}
+
+	if len(table) > 0 {
+		fmt.Fprintln(writer, "<h2>Runs</h2>")
+		writeHtmlTable(writer, "l"+strings.Repeat("r", len(table[0])-1), table[0], table[1:])
+	}
+
+	writeHtmlTail(writer)
+	return writer.Flush()
+}
@@ -0,0 +1,148 @@
+package gem5stats
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// MixClass is the committed instruction count of one op class.
+type MixClass struct {
+	Class string  `json:"class"`
+	Count uint64  `json:"count"`
+	Share float64 `json:"share"` // Fraction of all committed instructions
+}
+
+// Metrics are the headline numbers of a run besides the top-down breakdown.
+type Metrics struct {
+	Instructions uint64     `json:"instructions"`  // Committed instructions of all threads
+	Ops          uint64     `json:"ops"`           // Committed micro-ops of all threads
+	IPC          float64    `json:"ipc"`           // Instructions per cycle
+	CPI          float64    `json:"cpi"`           // Cycles per instruction
+	UopsPerInst  float64    `json:"uops_per_inst"` // Micro-ops per instruction
+	BranchMPKI   float64    `json:"branch_mpki"`   // Branch mispredictions per 1000 instructions
+	L1DMPKI      float64    `json:"l1d_mpki"`      // Cache misses per 1000 instructions
+	L1IMPKI      float64    `json:"l1i_mpki"`
+	L2MPKI       float64    `json:"l2_mpki"`
+	L3MPKI       float64    `json:"l3_mpki"` // Zero when the L2 is the last level
+	InstMix      []MixClass `json:"inst_mix"`
+}
+
+// calcMetrics derives IPC/CPI, the MPKIs and the instruction mix from the PMU.
+// Ratios with a zero denominator are left at zero.
+func calcMetrics(pmu *PMUStats) *Metrics {
+	m := new(Metrics)
+	for _, thread := range pmu.Threads {
+		m.Instructions += thread.NumInsts
+		m.Ops += thread.NumOps
+	}
+
+	insts := float64(m.Instructions)
+	if pmu.Cycles > 0 {
+		m.IPC = insts / float64(pmu.Cycles)
+	}
+	if m.Instructions > 0 {
+		m.CPI = float64(pmu.Cycles) / insts
+		m.UopsPerInst = float64(m.Ops) / insts
+		mpki := func(events uint64) float64 { return float64(events) / insts * 1000 }
+		m.BranchMPKI = mpki(pmu.MispredRetired)
+		m.L1DMPKI = mpki(pmu.L1D.Misses)
+		m.L1IMPKI = mpki(pmu.L1I.Misses)
+		m.L2MPKI = mpki(pmu.L2.Misses)
+		m.L3MPKI = mpki(pmu.L3.Misses)
+	}
+
+	var total uint64
+	for _, count := range pmu.InstMix {
+		total += count
+	}
+	for class, count := range pmu.InstMix {
+		if count == 0 {
+			continue
+		}
+		m.InstMix = append(m.InstMix, MixClass{Class: class, Count: count, Share: float64(count) / float64(total)})
+	}
+	sort.Slice(m.InstMix, func(i, j int) bool {
+		if m.InstMix[i].Count != m.InstMix[j].Count {
+			return m.InstMix[i].Count > m.InstMix[j].Count
+		}
+		return m.InstMix[i].Class < m.InstMix[j].Class
+	})
+	return m
+}
+
+// metricRows lists the scalar metrics in the order they are printed.
+func metricRows(m *Metrics) [][2]string {
+	if m == nil {
+		return nil
+	}
+	rows := [][2]string{
+		{"Instructions", fmt.Sprint(m.Instructions)},
+		{"Micro-ops", fmt.Sprint(m.Ops)},
+		{"IPC", fmt.Sprintf("%.4f", m.IPC)},
+		{"CPI", fmt.Sprintf("%.4f", m.CPI)},
+		{"Uops per Instruction", fmt.Sprintf("%.4f", m.UopsPerInst)},
+		{"Branch MPKI", fmt.Sprintf("%.4f", m.BranchMPKI)},
+		{"L1D MPKI", fmt.Sprintf("%.4f", m.L1DMPKI)},
+		{"L1I MPKI", fmt.Sprintf("%.4f", m.L1IMPKI)},
+		{"L2 MPKI", fmt.Sprintf("%.4f", m.L2MPKI)},
+	}
+	if m.L3MPKI > 0 {
+		rows = append(rows, [2]string{"L3 MPKI", fmt.Sprintf("%.4f", m.L3MPKI)})
+	}
+	return rows
+}
+
+func writeMdMetrics(writer *bufio.Writer, m *Metrics) {
+	fmt.Fprintln(writer, "## Performance Summary")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "| Metric | Value |")
+	fmt.Fprintln(writer, "| --- | ---: |")
+	for _, row := range metricRows(m) {
+		fmt.Fprintf(writer, "| %s | %s |\n", row[0], row[1])
+	}
+	fmt.Fprintln(writer)
+
+	if len(m.InstMix) == 0 {
+		return
+	}
+	fmt.Fprintln(writer, "## Instruction Mix")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "| Class | Count | Share |")
+	fmt.Fprintln(writer, "| --- | ---: | ---: |")
+	for _, c := range m.InstMix {
+		fmt.Fprintf(writer, "| %s | %d | %.2f%% |\n", mdEscape(c.Class), c.Count, c.Share*100)
+	}
+	fmt.Fprintln(writer)
+}
+
+func writeCsvMetrics(writer *csv.Writer, index string, core string, m *Metrics) {
+	for _, row := range metricRows(m) {
+		writer.Write([]string{index, core, "metric", row[0], "", row[1], "", "", ""})
+	}
+	if m == nil {
+		return
+	}
+	for _, c := range m.InstMix {
+		writer.Write([]string{index, core, "inst_mix", c.Class, "", fmt.Sprint(c.Count), formatFloat(c.Share * 100), "", ""})
+	}
+}
+
+func PrintMetrics(w io.Writer, m *Metrics) {
+	if m == nil {
+		return
+	}
+	fmt.Fprintln(w, "==================== Performance Summary ====================")
+	for _, row := range metricRows(m) {
+		fmt.Fprintf(w, "  %-22s  %s\n", row[0]+":", row[1])
+	}
+	if len(m.InstMix) > 0 {
+		fmt.Fprintln(w, "  --- Instruction Mix ---")
+		for _, c := range m.InstMix {
+			fmt.Fprintf(w, "  %-22s  %10d  (%6.2f%%)\n", c.Class+":", c.Count, c.Share*100)
+		}
+	}
+	fmt.Fprintln(w, "")
+}
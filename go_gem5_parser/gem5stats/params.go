@@ -0,0 +1,111 @@
+package gem5stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// UarchParams describes the simulated core for the TMA model. Latencies are
+// in core cycles unless stated otherwise.
+type UarchParams struct {
+	IssueWidth    uint64  `json:"issue_width"`    // Slots refilled per cycle after a squash
+	DispatchWidth uint64  `json:"dispatch_width"` // Slots per cycle, the base of every L1 fraction
+	L1Lat         float64 `json:"l1_latency"`     // L1D hit latency
+	L2Lat         float64 `json:"l2_latency"`     // L2 hit latency
+	L3Lat         float64 `json:"l3_latency"`     // L3 hit latency
+	DivLat        float64 `json:"div_latency"`    // Latency of the unpipelined divider
+	MemLat        float64 `json:"mem_latency"`    // Memory latency, overridden by MemLatNs when set
+	MemLatNs      float64 `json:"mem_latency_ns"` // Memory latency in ns, converted with ClockGHz
+	ClockGHz      float64 `json:"clock_ghz"`      // Core clock frequency
+
+	// Penalties that turn the event counts of the L3 split into cycles.
+	LSQBlockedLat float64 `json:"lsq_blocked_latency"` // Cycles lost per LSQ blocked by cache event, 0 uses L1Lat
+	MemQueueLat   float64 `json:"mem_queue_latency"`   // Cycles lost per stall of the Ruby mandatory queue or classic L1D without MSHRs
+	IQFullLat     float64 `json:"iq_full_latency"`     // Cycles of saturated issue ports per IQ full event
+
+	Source string `json:"source"` // Where the values came from
+}
+
+// DefaultParams returns the O3 configuration the model was first calibrated on.
+func DefaultParams() *UarchParams {
+	return &UarchParams{
+		IssueWidth:    8,
+		DispatchWidth: 8,
+		L1Lat:         3,
+		L2Lat:         8,
+		L3Lat:         30,
+		DivLat:        20,
+		MemLat:        90,
+		ClockGHz:      3,
+		MemQueueLat:   1,
+		IQFullLat:     1,
+		Source:        "defaults",
+	}
+}
+
+// lsqBlockedLatency returns the cycles lost per LSQ blocked by cache event.
+func (p *UarchParams) lsqBlockedLatency() float64 {
+	if p.LSQBlockedLat > 0 {
+		return p.LSQBlockedLat
+	}
+	return p.L1Lat
+}
+
+// memLatency returns the memory latency in core cycles.
+func (p *UarchParams) memLatency() float64 {
+	if p.MemLatNs > 0 && p.ClockGHz > 0 {
+		return p.MemLatNs * p.ClockGHz
+	}
+	return p.MemLat
+}
+
+// LoadParams overlays the JSON document read from r on top of params. Keys
+// that are missing from the document keep their current value. source names
+// the document in UarchParams.Source and in errors.
+func LoadParams(r io.Reader, source string, params *UarchParams) (*UarchParams, error) {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("invalid parameter file %s: %w", source, err)
+	}
+	loaded := *params
+	if err := json.Unmarshal(raw, &loaded); err != nil {
+		return nil, fmt.Errorf("invalid parameter file %s: %w", source, err)
+	}
+	// A latency in cycles replaces one in ns from an earlier layer, which
+	// memLatency would otherwise prefer.
+	var doc map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &doc); err == nil {
+		_, cycles := doc["mem_latency"]
+		_, ns := doc["mem_latency_ns"]
+		if cycles && !ns {
+			loaded.MemLatNs = 0
+		}
+	}
+	loaded.Source = source
+	if params.Source != DefaultParams().Source {
+		loaded.Source = params.Source + " + " + source
+	}
+
+	if loaded.IssueWidth == 0 || loaded.DispatchWidth == 0 {
+		return nil, fmt.Errorf("invalid parameter file %s: pipeline widths must be positive", source)
+	}
+	return &loaded, nil
+}
+
+func PrintParams(w io.Writer, params *UarchParams) {
+	fmt.Fprintln(w, "==================== TMA Model Parameters ====================")
+	fmt.Fprintf(w, "  %-20s  %s\n", "Source:", params.Source)
+	fmt.Fprintf(w, "  %-20s  %d\n", "Issue Width:", params.IssueWidth)
+	fmt.Fprintf(w, "  %-20s  %d\n", "Dispatch Width:", params.DispatchWidth)
+	fmt.Fprintf(w, "  %-20s  %.1f cycles\n", "L1 Latency:", params.L1Lat)
+	fmt.Fprintf(w, "  %-20s  %.1f cycles\n", "L2 Latency:", params.L2Lat)
+	fmt.Fprintf(w, "  %-20s  %.1f cycles\n", "L3 Latency:", params.L3Lat)
+	fmt.Fprintf(w, "  %-20s  %.1f cycles\n", "Divider Latency:", params.DivLat)
+	fmt.Fprintf(w, "  %-20s  %.1f cycles\n", "Memory Latency:", params.memLatency())
+	fmt.Fprintf(w, "  %-20s  %.2f GHz\n", "Clock:", params.ClockGHz)
+	fmt.Fprintf(w, "  %-20s  %.1f cycles/event\n", "LSQ Blocked Penalty:", params.lsqBlockedLatency())
+	fmt.Fprintf(w, "  %-20s  %.1f cycles/event\n", "Mem Queue Penalty:", params.MemQueueLat)
+	fmt.Fprintf(w, "  %-20s  %.1f cycles/event\n", "IQ Full Penalty:", params.IQFullLat)
+	fmt.Fprintln(w, "")
+}
@@ -0,0 +1,82 @@
+package gem5stats
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+const layerConfig = `{
+  "board": {
+    "path": "board",
+    "memory": {
+      "path": "board.memory.mem_ctrl",
+      "static_frontend_latency": 10000,
+      "static_backend_latency": 10000,
+      "dram": {"path": "board.memory.mem_ctrl.dram", "tCL": 14160, "tRCD": 14160, "tBURST": 3332}
+    }
+  }
+}`
+
+// TestParamsLayering checks that a -uarch file applied after config.json wins,
+// whichever unit the memory latency is given in.
+func TestParamsLayering(t *testing.T) {
+	tests := []struct {
+		name   string
+		uarch  string
+		memLat float64
+	}{
+		{"config only", `{}`, 51.652 * 3},
+		{"cycles", `{"mem_latency": 300}`, 300},
+		{"ns", `{"mem_latency_ns": 50}`, 150},
+		{"clock", `{"clock_ghz": 2}`, 51.652 * 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			params, err := LoadGem5Config(strings.NewReader(layerConfig), "config.json", DefaultParams())
+			if err != nil {
+				t.Fatal(err)
+			}
+			params, err = LoadParams(strings.NewReader(tt.uarch), "uarch.json", params)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := params.memLatency(); got < tt.memLat-1e-6 || got > tt.memLat+1e-6 {
+				t.Errorf("memory latency = %v cycles, want %v", got, tt.memLat)
+			}
+			if params.Source != "config.json + uarch.json" {
+				t.Errorf("Source = %q", params.Source)
+			}
+		})
+	}
+}
+
+// TestGem5ConfigCaches checks that the L1 data cache of classic SE configs is
+// found both by its dcache name and by the CPU port it is connected to, and
+// that the instruction cache is not taken for it.
+func TestGem5ConfigCaches(t *testing.T) {
+	const seConfig = `{
+  "system": {
+    "path": "system",
+    "cpu": [{
+      "path": "system.cpu",
+      "dispatchWidth": 8,
+      "%s": {"path": "system.cpu.%[1]s", "tag_latency": 1, "data_latency": 1, "cpu_side": {"role": "GEM5 RESPONDER", "peer": "system.cpu.icache_port"}},
+      "%s": {"path": "system.cpu.%[2]s", "tag_latency": 2, "data_latency": 2, "cpu_side": {"role": "GEM5 RESPONDER", "peer": "system.cpu.dcache_port"}}
+    }],
+    "l2": {"path": "system.l2", "tag_latency": 10, "data_latency": 10, "cpu_side": {"peer": ["system.tol2bus.mem_side_ports[0]"]}}
+  }
+}`
+	for _, names := range [][2]string{{"icache", "dcache"}, {"inst_side", "data_side"}} {
+		t.Run(names[1], func(t *testing.T) {
+			config := fmt.Sprintf(seConfig, names[0], names[1])
+			params, err := LoadGem5Config(strings.NewReader(config), "config.json", DefaultParams())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if params.L1Lat != 4 || params.L2Lat != 20 {
+				t.Errorf("L1Lat, L2Lat = %v, %v, want 4, 20", params.L1Lat, params.L2Lat)
+			}
+		})
+	}
+}
@@ -0,0 +1,338 @@
+// Package gem5stats parses gem5 stats.txt dumps and runs a top-down (TMA)
+// analysis on them. Functions read from io.Reader and write to io.Writer and
+// report problems as errors; the go_gem5_parser command is a thin wrapper.
+package gem5stats
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// namePattern is one wildcard or regular expression line of the interests file.
+type namePattern struct {
+	glob string
+	re   *regexp.Regexp
+}
+
+func (p namePattern) match(name string) bool {
+	if p.re != nil {
+		return p.re.MatchString(name)
+	}
+	ok, _ := path.Match(p.glob, name)
+	return ok
+}
+
+// Interest decides which stat names are kept. Each line of the interests file
+// is an exact stat name, a wildcard (board.cache_hierarchy.*.m_demand_*), a
+// regular expression prefixed with "re:", or any of those prefixed with "!"
+// to exclude the names it matches. An Interest is not changed once read, so
+// it may be shared by concurrent parsers.
+type Interest struct {
+	exact    map[string]bool
+	patterns []namePattern
+	excludes []namePattern
+	excluded map[string]bool
+}
+
+func newNamePattern(line string) (namePattern, error) {
+	if expr, ok := strings.CutPrefix(line, "re:"); ok {
+		re, err := regexp.Compile(expr)
+		return namePattern{re: re}, err
+	}
+	_, err := path.Match(line, "")
+	return namePattern{glob: line}, err
+}
+
+func isPattern(line string) bool {
+	return strings.HasPrefix(line, "re:") || strings.ContainsAny(line, "*?[")
+}
+
+// Match reports whether the stat name is interesting.
+func (in *Interest) Match(name string) bool {
+	ok := in.exact[name]
+	for i := 0; !ok && i < len(in.patterns); i++ {
+		ok = in.patterns[i].match(name)
+	}
+	if ok && in.excluded[name] {
+		ok = false
+	}
+	for i := 0; ok && i < len(in.excludes); i++ {
+		ok = !in.excludes[i].match(name)
+	}
+	return ok
+}
+
+// cachedMatch returns Match of interest with the results remembered per name,
+// since the same names repeat in every dump. It belongs to a single parse.
+func cachedMatch(interest *Interest) func(name string) bool {
+	resolved := make(map[string]bool)
+	return func(name string) bool {
+		ok, done := resolved[name]
+		if !done {
+			ok = interest.Match(name)
+			resolved[name] = ok
+		}
+		return ok
+	}
+}
+
+// GetInterest reads an interests file, one name or pattern per line, and
+// returns it together with the number of names and patterns to keep.
+func GetInterest(r io.Reader) (*Interest, int, error) {
+	interest := &Interest{
+		exact:    make(map[string]bool),
+		excluded: make(map[string]bool),
+	}
+
+	reader := bufio.NewReader(r)
+	for {
+		raw, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return nil, 0, readErr
+		}
+		line := strings.TrimSpace(raw)
+		if line != "" {
+			exclude := strings.HasPrefix(line, "!")
+			if exclude {
+				line = strings.TrimSpace(line[1:])
+			}
+
+			if !isPattern(line) {
+				if exclude {
+					interest.excluded[line] = true
+				} else {
+					interest.exact[line] = true
+				}
+			} else {
+				pattern, err := newNamePattern(line)
+				if err != nil {
+					return nil, 0, fmt.Errorf("invalid interest pattern %q: %w", line, err)
+				}
+				if exclude {
+					interest.excludes = append(interest.excludes, pattern)
+				} else {
+					interest.patterns = append(interest.patterns, pattern)
+				}
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+	}
+
+	return interest, len(interest.exact) + len(interest.patterns), nil
+}
+
+// parseValue parses one number of a stats line. gem5 writes nan, -nan, inf
+// and -inf for ratios with a zero denominator, those are reported as
+// undefined rather than as a number.
+func parseValue(s string) (float64, bool, error) {
+	switch strings.ToLower(strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")) {
+	case "nan", "inf", "infinity":
+		return 0, true, nil
+	}
+	val, err := strconv.ParseFloat(s, 64)
+	return val, false, err
+}
+
+func parseLine(line string, match func(name string) bool) (*Entry, bool) {
+	var dataPart, commentPart string
+	hashIdx := strings.Index(line, "#")
+	if hashIdx != -1 {
+		dataPart = line[:hashIdx]
+		commentPart = strings.TrimSpace(line[hashIdx+1:])
+	} else if hashIdx = strings.Index(line, "(Unspecified)"); hashIdx != -1 {
+		dataPart = line[:hashIdx]
+		commentPart = strings.TrimSpace(line[hashIdx+1:])
+	} else {
+		dataPart = line
+	}
+
+	fields := strings.Fields(dataPart)
+	if len(fields) == 0 {
+		return nil, false
+	}
+
+	entry := &Entry{
+		Description: commentPart,
+	}
+
+	i := len(fields) - 1
+
+	isPercentage := func(s string) bool {
+		return strings.HasSuffix(s, "%")
+	}
+
+	// Percentages of a zero total are nan%, those and unreadable ones are
+	// kept as written and flagged.
+	percentages := []struct {
+		value *float64
+		raw   *string
+	}{{&entry.Percentage2, &entry.RawPercentage2}, {&entry.Percentage1, &entry.RawPercentage1}}
+	for _, p := range percentages {
+		if i < 0 || !isPercentage(fields[i]) {
+			break
+		}
+		text := strings.TrimSuffix(fields[i], "%")
+		val, undefined, err := parseValue(text)
+		if undefined || err != nil {
+			entry.UndefinedPercentage = true
+			*p.raw = text
+		} else {
+			*p.value = val
+		}
+		entry.HasPercentage = true
+		i--
+	}
+
+	if i >= 0 {
+		val, undefined, err := parseValue(fields[i])
+		if err != nil {
+			return nil, false
+		}
+		if undefined {
+			entry.Undefined = true
+			entry.Raw = fields[i]
+		} else {
+			entry.Value = val
+		}
+		i--
+	} else {
+		return nil, false
+	}
+
+	if i >= 0 {
+		entry.Name = strings.Join(fields[:i+1], " ")
+	} else {
+		return nil, false
+	}
+
+	if !match(entry.Name) {
+		return nil, false
+	}
+
+	return entry, true
+}
+
+const (
+	dumpBeginMarker = "---------- Begin Simulation Statistics ----------"
+	dumpEndMarker   = "---------- End Simulation Statistics"
+)
+
+// Entry is one scalar line of a stats dump.
+type Entry struct {
+	Name          string  `json:"name"`
+	Value         float64 `json:"value"`
+	Percentage1   float64 `json:"percentage"`
+	Percentage2   float64 `json:"cumulative_percentage"`
+	Description   string  `json:"description"`
+	HasPercentage bool    `json:"has_percentage"`
+	Undefined     bool    `json:"undefined"`     // gem5 wrote nan or inf, Value is 0
+	Raw           string  `json:"raw,omitempty"` // The value as written by gem5 when Undefined
+
+	UndefinedPercentage bool   `json:"undefined_percentage"`                // A percentage was nan, inf or unreadable and is 0
+	RawPercentage1      string `json:"raw_percentage,omitempty"`            // Percentage1 as written by gem5 when undefined, without %
+	RawPercentage2      string `json:"raw_cumulative_percentage,omitempty"` // Percentage2 as written by gem5 when undefined, without %
+
+	line int // Position in the dump, keeps vector elements in gem5's order
+}
+
+// Dump is one "Begin/End Simulation Statistics" block of a stats file.
+type Dump struct {
+	Index    int              // 1-based position of the dump in the file
+	Entries  map[string]Entry // The interested entries of this dump
+	Stats    *TMAStats        // TMA analysis of Entries, filled by GetStats
+	Interval *Interval        // TMA of the time since the previous dump, filled by GetTimeSeries
+}
+
+// OpenStats opens a stats file for streaming. The content is decompressed on
+// the fly when it is gzip compressed (stats.txt.gz), whatever its name.
+func OpenStats(StatsFile string) (io.ReadCloser, error) {
+	file, err := os.Open(StatsFile)
+	if err != nil {
+		return nil, err
+	}
+	r, err := decompress(file)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("%s: %w", StatsFile, err)
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{r, file}, nil
+}
+
+// decompress unwraps gzip compressed input, recognised by its magic bytes.
+func decompress(r io.Reader) (io.Reader, error) {
+	reader := bufio.NewReaderSize(r, 1<<16)
+	if magic, _ := reader.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		return gzip.NewReader(reader)
+	}
+	return reader, nil
+}
+
+// Parselines splits a stats file on the Begin/End Simulation Statistics
+// markers and returns the interested entries of every dump in file order.
+// A file without markers is returned as a single dump. The input is streamed
+// line by line, so only the interested entries are kept in memory and lines
+// of any length are accepted. Gzip compressed input is decompressed.
+func Parselines(r io.Reader, interest *Interest) ([]Dump, error) {
+	input, err := decompress(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var dumps []Dump
+	current := -1 // index of the open dump, -1 between End and Begin markers
+
+	newDump := func() int {
+		dumps = append(dumps, Dump{
+			Index:   len(dumps) + 1,
+			Entries: make(map[string]Entry),
+		})
+		return len(dumps) - 1
+	}
+
+	match := cachedMatch(interest)
+	reader := bufio.NewReaderSize(input, 1<<16)
+	for lineNo := 1; ; lineNo++ {
+		raw, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, fmt.Errorf("line %d: %w", lineNo, err)
+		}
+		if raw == "" && err == io.EOF {
+			break
+		}
+
+		line := strings.TrimSpace(raw)
+		if strings.HasPrefix(line, dumpBeginMarker) {
+			current = newDump()
+			continue
+		}
+		if strings.HasPrefix(line, dumpEndMarker) {
+			current = -1
+			continue
+		}
+
+		entry, exist := parseLine(line, match)
+		if exist {
+			if current == -1 {
+				current = newDump()
+			}
+			entry.line = len(dumps[current].Entries)
+			dumps[current].Entries[(*entry).Name] = *entry
+		}
+		if err == io.EOF {
+			break
+		}
+	}
+	return dumps, nil
+}
@@ -0,0 +1,264 @@
+package gem5stats
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func mustInterest(t *testing.T, lines string) *Interest {
+	t.Helper()
+	interest, _, err := GetInterest(strings.NewReader(lines))
+	if err != nil {
+		t.Fatalf("GetInterest: %v", err)
+	}
+	return interest
+}
+
+func TestParseLine(t *testing.T) {
+	interest := mustInterest(t, "*\n!ignored.*\n")
+
+	tests := []struct {
+		name string
+		line string
+		ok   bool
+		want Entry
+	}{
+		{
+			name: "scalar",
+			line: "simTicks                                    333000000                       # Number of ticks simulated (Tick)",
+			ok:   true,
+			want: Entry{Name: "simTicks", Value: 333000000, Description: "Number of ticks simulated (Tick)"},
+		},
+		{
+			name: "fraction",
+			line: "board.processor.cores.core.ipc               2.000000                       # IPC: Instructions Per Cycle ((Count/Cycle))",
+			ok:   true,
+			want: Entry{Name: "board.processor.cores.core.ipc", Value: 2, Description: "IPC: Instructions Per Cycle ((Count/Cycle))"},
+		},
+		{
+			name: "percentages",
+			line: "board.processor.cores.core.commit.committedInstType_0::IntAlu      1400000     70.00%     70.50% # Class of committed instruction (Count)",
+			ok:   true,
+			want: Entry{Name: "board.processor.cores.core.commit.committedInstType_0::IntAlu", Value: 1400000,
+				Percentage1: 70, Percentage2: 70.5, HasPercentage: true, Description: "Class of committed instruction (Count)"},
+		},
+		{
+			name: "unspecified description",
+			line: "board.cache_hierarchy.ruby_system.m_outstandReqHistSeqr::mean     2.000000                       # (Unspecified)",
+			ok:   true,
+			want: Entry{Name: "board.cache_hierarchy.ruby_system.m_outstandReqHistSeqr::mean", Value: 2, Description: "(Unspecified)"},
+		},
+		{
+			name: "name with spaces",
+			line: "system.cpu.some stat   5   # A stat whose name has a space",
+			ok:   true,
+			want: Entry{Name: "system.cpu.some stat", Value: 5, Description: "A stat whose name has a space"},
+		},
+		{
+			name: "no description",
+			line: "finalTick 42",
+			ok:   true,
+			want: Entry{Name: "finalTick", Value: 42},
+		},
+		{
+			name: "inf",
+			line: "board.processor.cores.core.cpi   inf   # CPI: cycles per instruction ((Cycle/Count))",
+			ok:   true,
+			want: Entry{Name: "board.processor.cores.core.cpi", Undefined: true, Raw: "inf", Description: "CPI: cycles per instruction ((Cycle/Count))"},
+		},
+		{
+			name: "nan",
+			line: "board.processor.cores.core.ipc   nan   # IPC: Instructions Per Cycle ((Count/Cycle))",
+			ok:   true,
+			want: Entry{Name: "board.processor.cores.core.ipc", Undefined: true, Raw: "nan", Description: "IPC: Instructions Per Cycle ((Count/Cycle))"},
+		},
+		{
+			name: "negative nan",
+			line: "board.cache_hierarchy.l2-cache-0.overallMissRate::total   -nan   # Miss rate (Ratio)",
+			ok:   true,
+			want: Entry{Name: "board.cache_hierarchy.l2-cache-0.overallMissRate::total", Undefined: true, Raw: "-nan", Description: "Miss rate (Ratio)"},
+		},
+		{
+			name: "nan percentages",
+			line: "board.processor.cores.core.commit.committedInstType_0::IntAlu   0   nan%   nan%   # Class of committed instruction (Count)",
+			ok:   true,
+			want: Entry{Name: "board.processor.cores.core.commit.committedInstType_0::IntAlu", HasPercentage: true,
+				UndefinedPercentage: true, RawPercentage1: "nan", RawPercentage2: "nan", Description: "Class of committed instruction (Count)"},
+		},
+		{
+			name: "unreadable percentage",
+			line: "board.processor.cores.core.commit.committedInstType_0::IntAlu   5   x%   # Class of committed instruction (Count)",
+			ok:   true,
+			want: Entry{Name: "board.processor.cores.core.commit.committedInstType_0::IntAlu", Value: 5, HasPercentage: true,
+				UndefinedPercentage: true, RawPercentage2: "x", Description: "Class of committed instruction (Count)"},
+		},
+		{
+			name: "excluded",
+			line: "ignored.stat   5   # Left out by the interests",
+		},
+		{
+			name: "not a number",
+			line: "board.processor.cores.core.status   running   # Not a stat",
+		},
+		{
+			name: "value only",
+			line: "5   # No name",
+		},
+		{
+			name: "empty",
+			line: "",
+		},
+		{
+			name: "marker",
+			line: "---------- Begin Simulation Statistics ----------",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, ok := parseLine(tt.line, interest.Match)
+			if ok != tt.ok {
+				t.Fatalf("parseLine(%q) ok = %v, want %v", tt.line, ok, tt.ok)
+			}
+			if !ok {
+				return
+			}
+			if *entry != tt.want {
+				t.Errorf("parseLine(%q) = %+v, want %+v", tt.line, *entry, tt.want)
+			}
+		})
+	}
+}
+
+const twoDumps = `
+---------- Begin Simulation Statistics ----------
+simTicks    100   # Number of ticks simulated (Tick)
+other       1     # Not interesting
+---------- End Simulation Statistics   ----------
+
+---------- Begin Simulation Statistics ----------
+simTicks    250   # Number of ticks simulated (Tick)
+---------- End Simulation Statistics   ----------
+`
+
+func TestParselinesDumps(t *testing.T) {
+	dumps, err := Parselines(strings.NewReader(twoDumps), mustInterest(t, "simTicks\n"))
+	if err != nil {
+		t.Fatalf("Parselines: %v", err)
+	}
+	if len(dumps) != 2 {
+		t.Fatalf("got %d dumps, want 2", len(dumps))
+	}
+	for i, want := range []float64{100, 250} {
+		if dumps[i].Index != i+1 {
+			t.Errorf("dump %d has Index %d", i, dumps[i].Index)
+		}
+		if got := dumps[i].Entries["simTicks"].Value; got != want {
+			t.Errorf("dump %d simTicks = %v, want %v", i+1, got, want)
+		}
+		if _, ok := dumps[i].Entries["other"]; ok {
+			t.Errorf("dump %d kept a stat that is not interesting", i+1)
+		}
+	}
+}
+
+func TestParselinesGzipAndLongLines(t *testing.T) {
+	long := "simTicks   100   # " + strings.Repeat("x", 1<<20) + "\n"
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(long))
+	gz.Close()
+
+	dumps, err := Parselines(&buf, mustInterest(t, "simTicks\n"))
+	if err != nil {
+		t.Fatalf("Parselines: %v", err)
+	}
+	if len(dumps) != 1 || dumps[0].Entries["simTicks"].Value != 100 {
+		t.Fatalf("got %+v, want one dump with simTicks = 100", dumps)
+	}
+}
+
+func TestParselinesTruncatedGzip(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	gz.Write([]byte(strings.Repeat(twoDumps, 100)))
+	gz.Close()
+
+	truncated := bytes.NewReader(buf.Bytes()[:buf.Len()/2])
+	if _, err := Parselines(truncated, mustInterest(t, "simTicks\n")); err == nil {
+		t.Fatal("Parselines accepted a truncated gzip stream")
+	}
+}
+
+const emptyHistogram = `
+---------- Begin Simulation Statistics ----------
+system.cpu.lsq0.loadToUse::samples       0   # Distribution of load to use latency (Cycle)
+system.cpu.lsq0.loadToUse::mean        nan   # Distribution of load to use latency (Cycle)
+system.cpu.lsq0.loadToUse::stdev       nan   # Distribution of load to use latency (Cycle)
+system.cpu.lsq0.loadToUse::0-9           0   nan%   nan%   # Distribution of load to use latency (Cycle)
+system.cpu.lsq0.loadToUse::total         0   # Distribution of load to use latency (Cycle)
+system.cpu.commit.committedInstType_0::IntAlu   0   nan%   nan%   # Class of committed instruction (Count)
+system.cpu.commit.committedInstType_0::total    0   # Class of committed instruction (Count)
+---------- End Simulation Statistics   ----------
+`
+
+// TestGroupUndefined checks that nan lines stay flagged once grouped into
+// distributions and vectors.
+func TestGroupUndefined(t *testing.T) {
+	dumps, err := Parselines(strings.NewReader(emptyHistogram), mustInterest(t, "*\n"))
+	if err != nil {
+		t.Fatalf("Parselines: %v", err)
+	}
+	entries := dumps[0].Entries
+
+	dists := GroupDistributions(entries)
+	if len(dists) != 1 {
+		t.Fatalf("got %d distributions, want 1", len(dists))
+	}
+	dist := dists[0]
+	if dist.Undefined["mean"] != "nan" || dist.Undefined["stdev"] != "nan" {
+		t.Errorf("Undefined = %v, want mean and stdev as nan", dist.Undefined)
+	}
+	if got := dist.summary("mean", dist.Mean, "%.4f"); got != "nan" {
+		t.Errorf("mean is shown as %q, want nan", got)
+	}
+	if b := dist.Buckets[0]; !b.Undefined || b.RawPercentage != "nan" {
+		t.Errorf("bucket = %+v, want an undefined percentage", b)
+	}
+
+	vectors := GroupVectors(entries)
+	if len(vectors) != 1 {
+		t.Fatalf("got %d vectors, want 1", len(vectors))
+	}
+	if e := vectors[0].Elements[0]; !e.Undefined || e.RawPercentage != "nan" || e.RawCumulative != "nan" {
+		t.Errorf("element = %+v, want undefined percentages", e)
+	}
+}
+
+// TestGroupVectorsPercentages checks that only elements printed without
+// percentages get computed ones, continuing the cumulative of the others.
+func TestGroupVectorsPercentages(t *testing.T) {
+	const vector = `
+system.cpu.commit.committedInstType_0::IntAlu     50   49.00%   49.00%   # Class of committed instruction (Count)
+system.cpu.commit.committedInstType_0::MemRead    30   # Class of committed instruction (Count)
+system.cpu.commit.committedInstType_0::MemWrite   20   21.00%  100.00%   # Class of committed instruction (Count)
+system.cpu.commit.committedInstType_0::total     100   # Class of committed instruction (Count)
+`
+	dumps, err := Parselines(strings.NewReader(vector), mustInterest(t, "*\n"))
+	if err != nil {
+		t.Fatalf("Parselines: %v", err)
+	}
+	vectors := GroupVectors(dumps[0].Entries)
+	if len(vectors) != 1 {
+		t.Fatalf("got %d vectors, want 1", len(vectors))
+	}
+	want := [][2]float64{{49, 49}, {30, 79}, {21, 100}}
+	for i, e := range vectors[0].Elements {
+		if e.Percentage != want[i][0] || e.CumulativePercentage != want[i][1] {
+			t.Errorf("%s = %v%% %v%%, want %v%% %v%%", e.Key, e.Percentage, e.CumulativePercentage, want[i][0], want[i][1])
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package gem5stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// Profile maps the PMUStats fields to the stat names of one gem5 board layout.
+// Stat names are templates: {core} is replaced by the prefix of a core, {n} by
+// its index ("" on single core boards) and {t} by a thread index. Names may be
+// wildcards, and every name of a field that is present is added to it, so
+// alternatives and per bank stats can be listed side by side.
+type Profile struct {
+	Name      string              `json:"name"`
+	Hierarchy string              `json:"hierarchy"`    // HierarchyRuby or HierarchyClassic, informational
+	Detect    string              `json:"detect"`       // Regexp, the profile is chosen by -profile auto when a stat matches
+	Core      string              `json:"core"`         // Regexp, the 1st group is the core prefix and the 2nd its index
+	CoreStats map[string][]string `json:"core_stats"`   // PMUStats field path -> stats private to a core
+	Thread    map[string][]string `json:"thread_stats"` // ThreadData field -> stats of thread {t} of a core
+	Shared    map[string][]string `json:"shared_stats"` // PMUStats field path -> stats shared by all cores
+	GEM5TMA   map[string][]string `json:"gem5_tma"`     // TMAOutStats field -> gem5 reported TMA stats of a core
+	InstMix   []string            `json:"inst_mix"`     // Vector stats of a core counting committed instructions per op class
+
+	detect *regexp.Regexp
+	core   *regexp.Regexp
+}
+
+// o3CoreStats are the stat names of the O3 CPU relative to its prefix, shared
+// by every layout.
+func o3CoreStats() map[string][]string {
+	stats := map[string][]string{
+		// Base
+		"Cycles": {"{core}.numCycles"},
+
+		// Pipeline Slots
+		"SlotsIssued": {"{core}.instsIssued"},
+
+		// Pipeline Stalls
+		"MispredRetired":  {"{core}.commit.branchMispredicts"},
+		"FetchCycles":     {"{core}.fetch.status::icacheWaitResponse"},
+		"RecoveryCycles":  {"{core}.fetch.status::squashing"},
+		"MachineClears":   {"{core}.iew.dispatchStatus::squashing"},
+		"FetchStallSlots": {"{core}.fetch.fetchStallSlots"},
+
+		// Structural Stalls
+		"LoadQueueFull":  {"{core}.rename.LQFullEvents"},
+		"StoreQueueFull": {"{core}.rename.SQFullEvents"},
+		"InstQueueFull":  {"{core}.rename.IQFullEvents"},
+
+		// LSQ
+		"LSQBlockedByCache":  {"{core}.lsq*.blockedByCache"},
+		"MeanLoadAccessTime": {"{core}.lsq0.loadToUse::mean"},
+	}
+	for _, class := range divOpClasses {
+		stats["DivOps"] = append(stats["DivOps"], "{core}.commit.committedInstType_*::"+class)
+	}
+	return stats
+}
+
+func o3ThreadStats() map[string][]string {
+	return map[string][]string{
+		"NumInsts":     {"{core}.thread_{t}.numInsts"},
+		"NumOps":       {"{core}.thread_{t}.numOps"},
+		"SlotsRetired": {"{core}.commit.committedInstType_{t}::total"},
+		"OpsExecuted":  {"{core}.executeStats{t}.numInsts"},
+	}
+}
+
+func o3GEM5TMA() map[string][]string {
+	return map[string][]string{
+		"L1_retire":           {"{core}.L1_Retiring"},
+		"L1_badspec":          {"{core}.L1_BadSpeculation"},
+		"L1_frontend":         {"{core}.L1_FrontendBound"},
+		"L1_backend":          {"{core}.L1_BackendBound"},
+		"L0_fullfrontend":     {"{core}.L0_FullFrontendBound"},
+		"L0_frontendutil":     {"{core}.L0_FrontendUtil"},
+		"L0_BranchPrediction": {"{core}.L0_BranchPrediction"},
+	}
+}
+
+func o3InstMix() []string {
+	return []string{"{core}.commit.committedInstType_*"}
+}
+
+// classicCacheStats maps a classic cache (demandHits::total etc.) to a CacheStats field.
+func classicCacheStats(stats map[string][]string, field string, cache string) {
+	stats[field+".Access"] = append(stats[field+".Access"], cache+".demandAccesses::total")
+	stats[field+".Hits"] = append(stats[field+".Hits"], cache+".demandHits::total")
+	stats[field+".Misses"] = append(stats[field+".Misses"], cache+".demandMisses::total")
+}
+
+// rubyCacheStats maps a Ruby CacheMemory (m_demand_hits etc.) to a CacheStats field.
+func rubyCacheStats(stats map[string][]string, field string, cache string) {
+	stats[field+".Access"] = append(stats[field+".Access"], cache+".m_demand_accesses")
+	stats[field+".Hits"] = append(stats[field+".Hits"], cache+".m_demand_hits")
+	stats[field+".Misses"] = append(stats[field+".Misses"], cache+".m_demand_misses")
+}
+
+// builtinProfiles are tried in order by -profile auto.
+func builtinProfiles() []*Profile {
+	const ruby = "board.cache_hierarchy.ruby_system"
+
+	stdlibRuby := &Profile{
+		Name:      "stdlib-ruby",
+		Hierarchy: HierarchyRuby,
+		Detect:    `^board\.cache_hierarchy\.ruby_system\.`,
+		Core:      `^(board\.processor\.cores(\d*)\.core)\.`,
+		CoreStats: o3CoreStats(),
+		Thread:    o3ThreadStats(),
+		Shared: map[string][]string{
+			"Simticks":         {"simTicks"},
+			"MemReadReqs":      {"board.memory.mem_ctrl*.readReqs"},
+			"MemLevelParallel": {ruby + ".m_outstandReqHistSeqr::mean"},
+		},
+		GEM5TMA: o3GEM5TMA(),
+		InstMix: o3InstMix(),
+	}
+	// Sequencer of this core
+	stdlibRuby.CoreStats["MemQueueStallCount"] = []string{ruby + ".l1_controllers{n}.mandatoryQueue.m_stall_count"}
+	rubyCacheStats(stdlibRuby.CoreStats, "L1D", ruby+".l1_controllers{n}.L1Dcache")
+	rubyCacheStats(stdlibRuby.CoreStats, "L1I", ruby+".l1_controllers{n}.L1Icache")
+	rubyCacheStats(stdlibRuby.Shared, "L2", ruby+".l2_controllers*.L2cache")
+	rubyCacheStats(stdlibRuby.Shared, "L3", ruby+".l3_controllers*.L3cache")
+
+	stdlibClassic := &Profile{
+		Name:      "stdlib-classic",
+		Hierarchy: HierarchyClassic,
+		Detect:    `^board\.`,
+		Core:      `^(board\.processor\.cores(\d*)\.core)\.`,
+		CoreStats: o3CoreStats(),
+		Thread:    o3ThreadStats(),
+		Shared: map[string][]string{
+			"Simticks":    {"simTicks"},
+			"MemReadReqs": {"board.memory.mem_ctrl*.readReqs"},
+		},
+		GEM5TMA: o3GEM5TMA(),
+		InstMix: o3InstMix(),
+	}
+	// Times the L1D could not accept requests for lack of MSHRs. An event count
+	// like the Ruby stall count, blockedCycles would be weighed twice by
+	// mem_queue_latency. gem5 before v21 named it blocked.
+	stdlibClassic.CoreStats["MemQueueStallCount"] = []string{
+		"board.cache_hierarchy.l1dcaches{n}.blockedCauses::no_mshrs",
+		"board.cache_hierarchy.l1dcaches{n}.blocked::no_mshrs",
+	}
+	stdlibClassic.CoreStats["L1D.MissLatency"] = []string{"board.cache_hierarchy.l1dcaches{n}.demandMissLatency::total"}
+	classicCacheStats(stdlibClassic.CoreStats, "L1D", "board.cache_hierarchy.l1dcaches{n}")
+	classicCacheStats(stdlibClassic.CoreStats, "L1I", "board.cache_hierarchy.l1icaches{n}")
+	// l2cache when shared and l2caches when private
+	classicCacheStats(stdlibClassic.Shared, "L2", "board.cache_hierarchy.l2cache*")
+	classicCacheStats(stdlibClassic.Shared, "L3", "board.cache_hierarchy.l3cache*")
+
+	seClassic := &Profile{
+		Name:      "se-classic",
+		Hierarchy: HierarchyClassic,
+		Detect:    `^system\.cpu\d*\.`,
+		Core:      `^(system\.cpu(\d*))\.`,
+		CoreStats: o3CoreStats(),
+		Thread:    o3ThreadStats(),
+		Shared: map[string][]string{
+			"Simticks":    {"simTicks"},
+			"MemReadReqs": {"system.mem_ctrls*.readReqs"},
+		},
+		GEM5TMA: o3GEM5TMA(),
+		InstMix: o3InstMix(),
+	}
+	seClassic.CoreStats["MemQueueStallCount"] = []string{"{core}.dcache.blockedCauses::no_mshrs", "{core}.dcache.blocked::no_mshrs"}
+	seClassic.CoreStats["L1D.MissLatency"] = []string{"{core}.dcache.demandMissLatency::total"}
+	classicCacheStats(seClassic.CoreStats, "L1D", "{core}.dcache")
+	classicCacheStats(seClassic.CoreStats, "L1I", "{core}.icache")
+	classicCacheStats(seClassic.Shared, "L2", "system.l2")
+	classicCacheStats(seClassic.Shared, "L3", "system.l3")
+
+	profiles := []*Profile{stdlibRuby, stdlibClassic, seClassic}
+	for _, profile := range profiles {
+		if err := profile.compile(); err != nil {
+			panic(err)
+		}
+	}
+	return profiles
+}
+
+// fieldByPath resolves a dotted field path such as "L1D.Access".
+func fieldByPath(v reflect.Value, path string) (reflect.Value, error) {
+	for _, name := range strings.Split(path, ".") {
+		if v.Kind() != reflect.Struct {
+			return reflect.Value{}, fmt.Errorf("unknown field %q", path)
+		}
+		v = v.FieldByName(name)
+		if !v.IsValid() {
+			return reflect.Value{}, fmt.Errorf("unknown field %q", path)
+		}
+	}
+	if k := v.Kind(); k != reflect.Uint64 && k != reflect.Float64 {
+		return reflect.Value{}, fmt.Errorf("field %q is not a counter", path)
+	}
+	return v, nil
+}
+
+// compile checks the field paths and prepares the regular expressions.
+func (p *Profile) compile() error {
+	var err error
+	if p.core, err = regexp.Compile(p.Core); err != nil {
+		return fmt.Errorf("profile %s: core: %v", p.Name, err)
+	}
+	if p.core.NumSubexp() < 1 {
+		return fmt.Errorf("profile %s: core needs a group for the core prefix", p.Name)
+	}
+	if p.Detect != "" {
+		if p.detect, err = regexp.Compile(p.Detect); err != nil {
+			return fmt.Errorf("profile %s: detect: %v", p.Name, err)
+		}
+	}
+
+	checks := []struct {
+		fields map[string][]string
+		target any
+	}{
+		{p.CoreStats, PMUStats{}},
+		{p.Shared, PMUStats{}},
+		{p.Thread, ThreadData{}},
+		{p.GEM5TMA, TMAOutStats{}},
+	}
+	for _, check := range checks {
+		for field := range check.fields {
+			if _, err := fieldByPath(reflect.ValueOf(check.target), field); err != nil {
+				return fmt.Errorf("profile %s: %v", p.Name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// collect adds the stats of every mapped field to target, a pointer to the
+// struct the field paths refer to. It reports whether any stat was found.
+func collect(entries *map[string]Entry, fields map[string][]string, target any, key func(string) string) bool {
+	found := false
+	v := reflect.ValueOf(target).Elem()
+	for field, names := range fields {
+		f, err := fieldByPath(v, field)
+		if err != nil {
+			continue
+		}
+		for _, name := range names {
+			val, ok := lookup(entries, key(name))
+			if !ok {
+				continue
+			}
+			found = true
+			if f.Kind() == reflect.Uint64 {
+				f.SetUint(f.Uint() + uint64(val))
+			} else {
+				f.SetFloat(f.Float() + val)
+			}
+		}
+	}
+	return found
+}
+
+// collectMix sums the elements of the matching vector stats by sub-key, so
+// the op classes of every thread end up in one mix. ::total is skipped.
+func collectMix(entries *map[string]Entry, templates []string, key func(string) string) map[string]uint64 {
+	mix := make(map[string]uint64)
+	for _, template := range templates {
+		pattern := key(template)
+		for name, entry := range *entries {
+			parent, class, ok := splitSubKey(name)
+			if !ok || class == "total" {
+				continue
+			}
+			if match, _ := path.Match(pattern, parent); match {
+				mix[class] += uint64(entry.Value)
+			}
+		}
+	}
+	return mix
+}
+
+// ReadProfile decodes a JSON profile. name is used when the profile has none.
+func ReadProfile(r io.Reader, name string) (*Profile, error) {
+	profile := new(Profile)
+	if err := json.NewDecoder(r).Decode(profile); err != nil {
+		return nil, fmt.Errorf("invalid stat profile %s: %w", name, err)
+	}
+	if profile.Name == "" {
+		profile.Name = name
+	}
+	if err := profile.compile(); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// LoadProfile resolves a profile name: a built-in profile, the path to a JSON
+// profile, or "auto" to pick a built-in profile from the stat names.
+func LoadProfile(ProfileName string, entries *map[string]Entry) (*Profile, error) {
+	profiles := builtinProfiles()
+
+	if ProfileName == "auto" {
+		for _, profile := range profiles {
+			for name := range *entries {
+				if profile.detect.MatchString(name) {
+					return profile, nil
+				}
+			}
+		}
+		// Nothing to go by, keep the layout the parser was written for.
+		return profiles[0], nil
+	}
+
+	for _, profile := range profiles {
+		if profile.Name == ProfileName {
+			return profile, nil
+		}
+	}
+
+	file, err := os.Open(ProfileName)
+	if err != nil {
+		return nil, fmt.Errorf("unknown stat profile %q: %w", ProfileName, err)
+	}
+	defer file.Close()
+	return ReadProfile(file, ProfileName)
+}
@@ -0,0 +1,125 @@
+package gem5stats
+
+import "reflect"
+
+// Counter is one raw PMU counter that feeds a TMA category.
+type Counter struct {
+	Name  string  `json:"name"` // PMUStats field path, e.g. L1D.Misses
+	Value float64 `json:"value"`
+}
+
+// TMANode is one calculated TMA category and the categories it breaks down into.
+type TMANode struct {
+	Level    int        `json:"level"`
+	Name     string     `json:"name"`
+	Value    float64    `json:"value"` // Fraction of the pipeline slots
+	Share    float64    `json:"share"` // Percent of the parent category, of all slots on L1
+	Counters []Counter  `json:"counters"`
+	Children []*TMANode `json:"children"`
+}
+
+// categoryInputs lists for every category, by the names of tmaRows, the
+// PMUStats fields that calcL1, calcL2 and calcL3 read for its value and the
+// categories that value is derived from.
+var categoryInputs = map[string]struct {
+	fields []string
+	from   []string
+}{
+	"Retiring":        {fields: []string{"Cycles", "SlotsRetired"}},
+	"Bad Speculation": {fields: []string{"Cycles", "SlotsIssued", "SlotsRetired", "RecoveryCycles"}},
+	"Frontend Bound":  {fields: []string{"Cycles", "FetchStallSlots"}},
+	"Backend Bound":   {from: []string{"Retiring", "Bad Speculation", "Frontend Bound"}},
+
+	"Fetch Latency":   {fields: []string{"Cycles", "FetchCycles"}},
+	"Fetch Bandwidth": {from: []string{"Frontend Bound", "Fetch Latency"}},
+	"Branch Mispred":  {fields: []string{"MispredRetired", "MachineClears"}, from: []string{"Bad Speculation"}},
+	"Machine Clears":  {fields: []string{"MispredRetired", "MachineClears"}, from: []string{"Bad Speculation"}},
+	"Memory Bound": {
+		fields: []string{"Cycles", "L1D.Misses", "L2.Misses", "L3.Access", "L3.Misses", "MemLevelParallel"},
+		from:   []string{"Backend Bound"}, // Memory Bound is capped at Backend Bound
+	},
+	"Core Bound": {from: []string{"Backend Bound", "Memory Bound"}},
+
+	// Memory Bound and Core Bound are shared out by the stall estimates of all
+	// their children, so every child reads every weight.
+	"L1 Bound":          {fields: memoryWeights, from: []string{"Memory Bound"}},
+	"L2 Bound":          {fields: memoryWeights, from: []string{"Memory Bound"}},
+	"L3 Bound":          {fields: memoryWeights, from: []string{"Memory Bound"}},
+	"DRAM Bound":        {fields: memoryWeights, from: []string{"Memory Bound"}},
+	"Divider":           {fields: coreWeights, from: []string{"Core Bound"}},
+	"Ports Utilization": {fields: coreWeights, from: []string{"Core Bound"}},
+}
+
+var (
+	memoryWeights = []string{"LSQBlockedByCache", "L1D.Misses", "L2.Misses", "L3.Access", "L3.Misses", "MemQueueStallCount"}
+	coreWeights   = []string{"DivOps", "InstQueueFull"}
+)
+
+// categoryCounters returns the fields a category is calculated from, its own
+// first and then those of the categories it is derived from, without repeats.
+func categoryCounters(name string) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	var add func(name string)
+	add = func(name string) {
+		inputs := categoryInputs[name]
+		for _, field := range inputs.fields {
+			if !seen[field] {
+				seen[field] = true
+				paths = append(paths, field)
+			}
+		}
+		for _, from := range inputs.from {
+			add(from)
+		}
+	}
+	add(name)
+	return paths
+}
+
+// pmuCounters reads the counters named by paths from pmu.
+func pmuCounters(pmu *PMUStats, paths []string) []Counter {
+	if pmu == nil {
+		return nil
+	}
+	counters := make([]Counter, 0, len(paths))
+	for _, path := range paths {
+		f, err := fieldByPath(reflect.ValueOf(pmu).Elem(), path)
+		if err != nil {
+			continue
+		}
+		c := Counter{Name: path}
+		if f.Kind() == reflect.Float64 {
+			c.Value = f.Float()
+		} else {
+			c.Value = float64(f.Uint())
+		}
+		counters = append(counters, c)
+	}
+	return counters
+}
+
+// TMATree returns the calculated L1 categories of stats, each holding its L2
+// and L3 breakdown and the PMU counters it was calculated from.
+func TMATree(stats *TMAStats) []*TMANode {
+	rows := tmaRows(stats)
+	var roots []*TMANode
+	nodes := make(map[string]*TMANode)
+	for _, row := range rows {
+		node := &TMANode{
+			Level:    row.Level,
+			Name:     row.Name,
+			Value:    row.Value,
+			Share:    row.Value * 100,
+			Counters: pmuCounters(stats.pmu, categoryCounters(row.Name)),
+		}
+		nodes[row.Name] = node
+		if parent, ok := nodes[row.Parent]; ok {
+			node.Share = parentShare(rows, row)
+			parent.Children = append(parent.Children, node)
+		} else {
+			roots = append(roots, node)
+		}
+	}
+	return roots
+}
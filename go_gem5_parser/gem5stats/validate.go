@@ -0,0 +1,246 @@
+package gem5stats
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Kinds of Warning.
+const (
+	WarnMissingStat = "missing_stat" // A stat the model needs was not found
+	WarnUndefined   = "undefined"    // A metric came out NaN or Inf and was set to 0
+	WarnOutOfRange  = "out_of_range" // A fraction is outside [0, 1]
+	WarnSum         = "sum"          // The L1 categories do not add up to 1
+)
+
+// Warning is a problem found in the inputs or the results of the TMA model.
+type Warning struct {
+	Core    string `json:"core"` // Core label, empty for the aggregated view and shared stats
+	Kind    string `json:"kind"`
+	Metric  string `json:"metric"`
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	where := ""
+	if w.Core != "" {
+		where = w.Core + ": "
+	}
+	return fmt.Sprintf("%s%s [%s] %s", where, w.Metric, w.Kind, w.Message)
+}
+
+// requiredStats are the fields without which the L1/L2 results are meaningless.
+var requiredStats = struct {
+	core   []string
+	thread []string
+	shared []string
+}{
+	core:   []string{"Cycles", "SlotsIssued", "FetchStallSlots", "FetchCycles", "RecoveryCycles", "MispredRetired", "L1D.Misses"},
+	thread: []string{"SlotsRetired"},
+	shared: []string{"L2.Misses"},
+}
+
+// mapped reports whether any stat of the field resolves in entries.
+func mapped(entries *map[string]Entry, names []string, key func(string) string) bool {
+	for _, name := range names {
+		if _, ok := lookup(entries, key(name)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// checkMissing lists the required stats that the profile could not find.
+func checkMissing(entries *map[string]Entry, profile *Profile, cores []coreRef) []Warning {
+	var warnings []Warning
+	missing := func(core string, field string, names []string, key func(string) string) {
+		resolved := make([]string, len(names))
+		for i, name := range names {
+			resolved[i] = key(name)
+		}
+		warnings = append(warnings, Warning{
+			Core:    core,
+			Kind:    WarnMissingStat,
+			Metric:  field,
+			Message: fmt.Sprintf("none of %s found, check the interests file and -profile", strings.Join(resolved, ", ")),
+		})
+	}
+
+	if len(cores) == 0 {
+		warnings = append(warnings, Warning{
+			Kind:    WarnMissingStat,
+			Metric:  "cores",
+			Message: fmt.Sprintf("no stat matches the core pattern %s of profile %s", profile.Core, profile.Name),
+		})
+	}
+	for _, core := range cores {
+		for _, field := range requiredStats.core {
+			if !mapped(entries, profile.CoreStats[field], core.key) {
+				missing(core.label, field, profile.CoreStats[field], core.key)
+			}
+		}
+		thread0 := func(name string) string { return core.key(strings.ReplaceAll(name, "{t}", "0")) }
+		for _, field := range requiredStats.thread {
+			if !mapped(entries, profile.Thread[field], thread0) {
+				missing(core.label, "Threads."+field, profile.Thread[field], thread0)
+			}
+		}
+	}
+	same := func(name string) string { return name }
+	for _, field := range requiredStats.shared {
+		if !mapped(entries, profile.Shared[field], same) {
+			missing("", field, profile.Shared[field], same)
+		}
+	}
+	return warnings
+}
+
+// undefinedStats returns the entries matching key that gem5 wrote as nan or
+// inf, ordered by name.
+func undefinedStats(entries *map[string]Entry, key string) []Entry {
+	var found []Entry
+	for name, entry := range *entries {
+		if !entry.Undefined {
+			continue
+		}
+		if ok, _ := path.Match(key, name); ok || name == key {
+			found = append(found, entry)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Name < found[j].Name })
+	return found
+}
+
+// checkUndefined flags the stats of the profile that gem5 wrote as nan or inf.
+// They are counted as 0 by the model, which may skew the results.
+func checkUndefined(entries *map[string]Entry, profile *Profile, cores []coreRef) []Warning {
+	var warnings []Warning
+	check := func(core string, prefix string, fields map[string][]string, key func(string) string) {
+		names := make([]string, 0, len(fields))
+		for field := range fields {
+			names = append(names, field)
+		}
+		sort.Strings(names)
+		for _, field := range names {
+			for _, name := range fields[field] {
+				for _, entry := range undefinedStats(entries, key(name)) {
+					warnings = append(warnings, Warning{
+						Core:    core,
+						Kind:    WarnUndefined,
+						Metric:  prefix + field,
+						Message: fmt.Sprintf("%s is %s in the stats, counted as 0", entry.Name, entry.Raw),
+					})
+				}
+			}
+		}
+	}
+
+	for _, core := range cores {
+		check(core.label, "", profile.CoreStats, core.key)
+		threads := func(name string) string { return core.key(strings.ReplaceAll(name, "{t}", "*")) }
+		check(core.label, "Threads.", profile.Thread, threads)
+		check(core.label, "gem5_tma.", profile.GEM5TMA, core.key)
+	}
+	check("", "", profile.Shared, func(name string) string { return name })
+	return warnings
+}
+
+// checkLevel replaces NaN/Inf metrics of one TMA level by 0 and flags every
+// fraction outside [0, 1]. Metrics are named after their JSON keys.
+func checkLevel(level string, metrics any) []Warning {
+	var warnings []Warning
+	v := reflect.ValueOf(metrics).Elem()
+	t := v.Type()
+	for i := 0; i < v.NumField(); i++ {
+		f := v.Field(i)
+		name := level + "." + strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		val := f.Float()
+		switch {
+		case math.IsNaN(val) || math.IsInf(val, 0):
+			warnings = append(warnings, Warning{
+				Kind:    WarnUndefined,
+				Metric:  name,
+				Message: fmt.Sprintf("%v, a denominator is zero; reported as 0", val),
+			})
+			f.SetFloat(0)
+		case val < -1e-9 || val > 1+1e-9:
+			warnings = append(warnings, Warning{
+				Kind:    WarnOutOfRange,
+				Metric:  name,
+				Message: fmt.Sprintf("%.4f is not a fraction of the pipeline slots", val),
+			})
+		}
+	}
+	return warnings
+}
+
+// validateTMA sanitizes the calculated levels of stats in place and returns
+// what was wrong with them.
+func validateTMA(stats *TMAStats) []Warning {
+	var warnings []Warning
+
+	if stats.pmu.Cycles == 0 {
+		warnings = append(warnings, Warning{
+			Kind:    WarnUndefined,
+			Metric:  "pmu.cycles",
+			Message: "zero cycles, every TMA fraction is undefined",
+		})
+	}
+	if stats.pmu.MemLevelParallel <= 0 && stats.pmu.L1D.Misses > 0 {
+		warnings = append(warnings, Warning{
+			Kind:    WarnMissingStat,
+			Metric:  "pmu.mem_level_parallel",
+			Message: "no memory level parallelism, memory bound assumes 1 miss in flight",
+		})
+	}
+
+	warnings = append(warnings, checkLevel("tma_l1", stats.tmaL1)...)
+	warnings = append(warnings, checkLevel("tma_l2", stats.tmaL2)...)
+	warnings = append(warnings, checkLevel("tma_l3", stats.tmaL3)...)
+
+	l1 := stats.tmaL1
+	if sum := l1.L1_retire + l1.L1_badspec + l1.L1_frontend + l1.L1_backend; stats.pmu.Cycles != 0 && math.Abs(sum-1) > 1e-6 {
+		warnings = append(warnings, Warning{
+			Kind:    WarnSum,
+			Metric:  "tma_l1",
+			Message: fmt.Sprintf("categories add up to %.4f instead of 1", sum),
+		})
+	}
+
+	return warnings
+}
+
+// allWarnings returns the warnings of stats followed by those of its cores.
+// A single core repeats the aggregate, so its warnings are left out.
+func (stats *TMAStats) allWarnings() []Warning {
+	if stats == nil {
+		return nil
+	}
+	warnings := append([]Warning(nil), stats.warnings...)
+	if len(stats.cores) < 2 {
+		return warnings
+	}
+	for _, core := range stats.cores {
+		for _, w := range core.warnings {
+			w.Core = core.core
+			warnings = append(warnings, w)
+		}
+	}
+	return warnings
+}
+
+func PrintWarnings(w io.Writer, warnings []Warning) {
+	if len(warnings) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "==================== Warnings ====================")
+	for _, warning := range warnings {
+		fmt.Fprintln(w, " ", warning)
+	}
+	fmt.Fprintln(w, "")
+}
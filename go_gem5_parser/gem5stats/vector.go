@@ -0,0 +1,170 @@
+package gem5stats
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// VectorElement is one "::" sub-key of a vector stat.
+type VectorElement struct {
+	Key                  string  `json:"key"`
+	Value                float64 `json:"value"`
+	Percentage           float64 `json:"percentage"`
+	CumulativePercentage float64 `json:"cumulative_percentage"`
+	HasPercentage        bool    `json:"has_percentage"` // gem5 printed the percentages, otherwise they are computed from the total
+
+	Undefined     bool   `json:"undefined"`                // gem5 wrote nan or inf for the value or a percentage
+	Raw           string `json:"raw,omitempty"`            // The value as written by gem5 when undefined
+	RawPercentage string `json:"raw_percentage,omitempty"` // The percentages as written by gem5 when undefined, without %
+	RawCumulative string `json:"raw_cumulative_percentage,omitempty"`
+}
+
+// Vector groups the sub-keys of a gem5 vector stat, e.g. the instruction mix
+// commit.committedInstType_0::IntAlu, ::MemRead, ... under their parent.
+type Vector struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	Total       float64         `json:"total"`
+	HasTotal    bool            `json:"has_total"`           // gem5 wrote a ::total line, otherwise Total is the sum of the elements
+	RawTotal    string          `json:"raw_total,omitempty"` // The ::total line as written by gem5 when nan or inf
+	Elements    []VectorElement `json:"elements"`
+}
+
+// Element returns the value of one sub-key.
+func (v *Vector) Element(key string) (float64, bool) {
+	for _, e := range v.Elements {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return 0, false
+}
+
+// GroupVectors collects the vector stats among the entries, ordered by name,
+// with the elements in the order gem5 wrote them. Distributions are left to
+// GroupDistributions. Elements that gem5 printed without percentages get them
+// computed from the total, the others keep what gem5 printed.
+func GroupVectors(entries map[string]Entry) []Vector {
+	groups := make(map[string][]Entry)
+	for name, entry := range entries {
+		if parent, _, ok := splitSubKey(name); ok {
+			groups[parent] = append(groups[parent], entry)
+		}
+	}
+
+	var vectors []Vector
+	for parent, group := range groups {
+		sort.Slice(group, func(i, j int) bool {
+			return group[i].line < group[j].line
+		})
+
+		vec := Vector{Name: parent}
+		isDist := false
+		var sum float64
+		for _, entry := range group {
+			_, key, _ := splitSubKey(entry.Name)
+			if _, ok := distributionFields[key]; ok {
+				isDist = true
+				break
+			}
+			if vec.Description == "" {
+				vec.Description = entry.Description
+			}
+			if key == "total" {
+				vec.Total = entry.Value
+				vec.HasTotal = true
+				vec.RawTotal = entry.Raw
+				continue
+			}
+			vec.Elements = append(vec.Elements, VectorElement{
+				Key:                  key,
+				Value:                entry.Value,
+				Percentage:           entry.Percentage1,
+				CumulativePercentage: entry.Percentage2,
+				HasPercentage:        entry.HasPercentage,
+				Undefined:            entry.Undefined || entry.UndefinedPercentage,
+				Raw:                  entry.Raw,
+				RawPercentage:        entry.RawPercentage1,
+				RawCumulative:        entry.RawPercentage2,
+			})
+			sum += entry.Value
+		}
+		if isDist {
+			continue
+		}
+
+		if !vec.HasTotal {
+			vec.Total = sum
+		}
+		if vec.Total != 0 {
+			// The cumulative runs on from the previous element, whether gem5
+			// printed that one or not.
+			var cumulative float64
+			for i := range vec.Elements {
+				e := &vec.Elements[i]
+				if !e.HasPercentage {
+					e.Percentage = e.Value / vec.Total * 100
+					e.CumulativePercentage = cumulative + e.Percentage
+				}
+				cumulative = e.CumulativePercentage
+			}
+		}
+		vectors = append(vectors, vec)
+	}
+
+	sort.Slice(vectors, func(i, j int) bool {
+		return vectors[i].Name < vectors[j].Name
+	})
+	return vectors
+}
+
+// vectorOwner returns the SimObject a vector stat belongs to, e.g.
+// board.processor.cores.core.commit for commit.committedInstType_0.
+func vectorOwner(name string) string {
+	if i := strings.LastIndex(name, "."); i != -1 {
+		return name[:i]
+	}
+	return ""
+}
+
+func writeMdVectors(writer *bufio.Writer, vectors []Vector) {
+	fmt.Fprintln(writer, "## Vectors")
+	fmt.Fprintln(writer)
+	owner := ""
+	for i, vec := range vectors {
+		if o := vectorOwner(vec.Name); i == 0 || o != owner {
+			owner = o
+			fmt.Fprintf(writer, "### %s\n\n", mdEscape(owner))
+		}
+		fmt.Fprintf(writer, "#### %s\n\n", mdEscape(strings.TrimPrefix(vec.Name, owner+".")))
+		if vec.Description != "" {
+			fmt.Fprintf(writer, "%s\n\n", mdEscape(vec.Description))
+		}
+		fmt.Fprintln(writer, "| Key | Value | Percentage | Cumulative |")
+		fmt.Fprintln(writer, "| --- | ---: | ---: | ---: |")
+		for _, e := range vec.Elements {
+			fmt.Fprintf(writer, "| %s | %s | %s%% | %s%% |\n", mdEscape(e.Key), rawOr(e.Raw, formatFloat(e.Value)),
+				rawOr(e.RawPercentage, fmt.Sprintf("%.2f", e.Percentage)), rawOr(e.RawCumulative, fmt.Sprintf("%.2f", e.CumulativePercentage)))
+		}
+		fmt.Fprintf(writer, "| **total** | %s | | |\n", rawOr(vec.RawTotal, formatFloat(vec.Total)))
+		fmt.Fprintln(writer)
+	}
+}
+
+func PrintVectors(w io.Writer, vectors []Vector) {
+	if len(vectors) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "==================== Vectors ====================")
+	for _, vec := range vectors {
+		fmt.Fprintf(w, "  %s (total %s)\n", vec.Name, rawOr(vec.RawTotal, formatFloat(vec.Total)))
+		for _, e := range vec.Elements {
+			fmt.Fprintf(w, "    %-24s %12s %7s%%  %s\n", e.Key, rawOr(e.Raw, formatFloat(e.Value)),
+				rawOr(e.RawPercentage, fmt.Sprintf("%.2f", e.Percentage)), histogramBar(e.Percentage, 40))
+		}
+	}
+	fmt.Fprintln(w, "")
+}
@@ -0,0 +1,725 @@
+package gem5stats
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DataWriter renders analysed dumps in one output format.
+type DataWriter interface {
+	Write(dumps []Dump, out io.Writer) error
+}
+
+// TextWriter dumps the selected entries as space separated lines.
+type TextWriter struct{}
+
+// CsvWriter emits the TMA metrics and the selected entries as one RFC 4180 table.
+type CsvWriter struct{}
+
+// MdWriter emits the TMA metrics and the selected entries as Markdown tables.
+type MdWriter struct{}
+
+// JsonWriter serializes the stats and TMA results as an indented JSON document.
+type JsonWriter struct{}
+
+// YamlWriter serializes the same document as JsonWriter in YAML.
+type YamlWriter struct{}
+
+// ReportSchemaVersion is bumped whenever a key of Report changes meaning.
+const ReportSchemaVersion = 4
+
+// Report is the machine readable document produced by the JSON and YAML writers.
+type Report struct {
+	SchemaVersion int              `json:"schema_version"`
+	Params        *UarchParams     `json:"params"`
+	TimeSeries    []IntervalReport `json:"time_series"`
+	Dumps         []DumpReport     `json:"dumps"`
+}
+
+// IntervalReport is one point of the TMA time series inside a Report.
+type IntervalReport struct {
+	Dump      int         `json:"dump"`
+	StartTick uint64      `json:"start_tick"`
+	EndTick   uint64      `json:"end_tick"`
+	Cycles    uint64      `json:"cycles"`
+	TMAL1     *L1TMAStats `json:"tma_l1"`
+	TMAL2     *L2TMAStats `json:"tma_l2"`
+	TMAL3     *L3TMAStats `json:"tma_l3"`
+	Metrics   *Metrics    `json:"metrics"`
+	Warnings  []Warning   `json:"warnings"`
+}
+
+// DumpReport holds the results of one stats dump inside a Report.
+type DumpReport struct {
+	Index         int            `json:"index"`
+	GEM5TMA       *TMAOutStats   `json:"gem5_tma"`
+	TMAL1         *L1TMAStats    `json:"tma_l1"`
+	TMAL2         *L2TMAStats    `json:"tma_l2"`
+	TMAL3         *L3TMAStats    `json:"tma_l3"`
+	PMU           *PMUStats      `json:"pmu"`
+	Metrics       *Metrics       `json:"metrics"`
+	Cores         []CoreReport   `json:"cores"`
+	Comparison    []Comparison   `json:"comparison"`
+	Warnings      []Warning      `json:"warnings"`
+	Vectors       []Vector       `json:"vectors"`
+	Distributions []Distribution `json:"distributions"`
+	Stats         []Entry        `json:"stats"`
+}
+
+// CoreReport holds the analysis of a single core inside a DumpReport.
+type CoreReport struct {
+	Core    string       `json:"core"`
+	GEM5TMA *TMAOutStats `json:"gem5_tma"`
+	TMAL1   *L1TMAStats  `json:"tma_l1"`
+	TMAL2   *L2TMAStats  `json:"tma_l2"`
+	TMAL3   *L3TMAStats  `json:"tma_l3"`
+	PMU     *PMUStats    `json:"pmu"`
+	Metrics *Metrics     `json:"metrics"`
+}
+
+func newReport(dumps []Dump) *Report {
+	report := &Report{
+		SchemaVersion: ReportSchemaVersion,
+		Params:        dumpParams(dumps),
+		TimeSeries:    make([]IntervalReport, 0, len(dumps)),
+		Dumps:         make([]DumpReport, 0, len(dumps)),
+	}
+	for _, dump := range dumps {
+		if iv := dump.Interval; iv != nil {
+			report.TimeSeries = append(report.TimeSeries, IntervalReport{
+				Dump:      dump.Index,
+				StartTick: iv.StartTick,
+				EndTick:   iv.EndTick,
+				Cycles:    iv.Stats.pmu.Cycles,
+				TMAL1:     iv.Stats.tmaL1,
+				TMAL2:     iv.Stats.tmaL2,
+				TMAL3:     iv.Stats.tmaL3,
+				Metrics:   iv.Stats.metrics,
+				Warnings:  iv.Stats.allWarnings(),
+			})
+		}
+
+		dr := DumpReport{
+			Index:         dump.Index,
+			Vectors:       GroupVectors(dump.Entries),
+			Distributions: GroupDistributions(dump.Entries),
+			Stats:         sortedEntries(dump.Entries),
+		}
+		if stats := dump.Stats; stats != nil {
+			dr.GEM5TMA = stats.mytma
+			dr.TMAL1 = stats.tmaL1
+			dr.TMAL2 = stats.tmaL2
+			dr.TMAL3 = stats.tmaL3
+			dr.Comparison = CompareTMA(stats)
+			dr.Warnings = stats.allWarnings()
+			dr.PMU = stats.pmu
+			dr.Metrics = stats.metrics
+			dr.Cores = make([]CoreReport, 0, len(stats.cores))
+			for _, core := range stats.cores {
+				dr.Cores = append(dr.Cores, CoreReport{
+					Core:    core.core,
+					GEM5TMA: core.mytma,
+					TMAL1:   core.tmaL1,
+					TMAL2:   core.tmaL2,
+					TMAL3:   core.tmaL3,
+					PMU:     core.pmu,
+					Metrics: core.metrics,
+				})
+			}
+		}
+		report.Dumps = append(report.Dumps, dr)
+	}
+	return report
+}
+
+// dumpParams returns the model parameters the dumps were analysed with.
+func dumpParams(dumps []Dump) *UarchParams {
+	for _, dump := range dumps {
+		if dump.Stats != nil && dump.Stats.params != nil {
+			return dump.Stats.params
+		}
+	}
+	return nil
+}
+
+// paramRows lists the model parameters as name/value pairs for the table writers.
+func paramRows(params *UarchParams) [][2]string {
+	if params == nil {
+		return nil
+	}
+	return [][2]string{
+		{"Source", params.Source},
+		{"Issue Width", strconv.FormatUint(params.IssueWidth, 10)},
+		{"Dispatch Width", strconv.FormatUint(params.DispatchWidth, 10)},
+		{"L1 Latency", formatFloat(params.L1Lat)},
+		{"L2 Latency", formatFloat(params.L2Lat)},
+		{"L3 Latency", formatFloat(params.L3Lat)},
+		{"Divider Latency", formatFloat(params.DivLat)},
+		{"Memory Latency", formatFloat(params.memLatency())},
+		{"Clock GHz", formatFloat(params.ClockGHz)},
+		{"LSQ Blocked Penalty", formatFloat(params.lsqBlockedLatency())},
+		{"Mem Queue Penalty", formatFloat(params.MemQueueLat)},
+		{"IQ Full Penalty", formatFloat(params.IQFullLat)},
+	}
+}
+
+// tmaRow is one calculated TMA metric together with the category it breaks down.
+type tmaRow struct {
+	Level  int
+	Name   string
+	Parent string
+	Value  float64
+}
+
+// tmaRows flattens the calculated L1/L2/L3 metrics in the order they are printed.
+func tmaRows(stats *TMAStats) []tmaRow {
+	if stats == nil || stats.tmaL1 == nil || stats.tmaL2 == nil || stats.tmaL3 == nil {
+		return nil
+	}
+	l1 := stats.tmaL1
+	l2 := stats.tmaL2
+	l3 := stats.tmaL3
+	return []tmaRow{
+		{1, "Retiring", "", l1.L1_retire},
+		{1, "Bad Speculation", "", l1.L1_badspec},
+		{1, "Frontend Bound", "", l1.L1_frontend},
+		{1, "Backend Bound", "", l1.L1_backend},
+		{2, "Fetch Latency", "Frontend Bound", l2.L2_fetch_latency},
+		{2, "Fetch Bandwidth", "Frontend Bound", l2.L2_fetch_bandwidth},
+		{2, "Branch Mispred", "Bad Speculation", l2.L2_branch_mispredict},
+		{2, "Machine Clears", "Bad Speculation", l2.L2_machine_clear},
+		{2, "Memory Bound", "Backend Bound", l2.L2_memory_bound},
+		{2, "Core Bound", "Backend Bound", l2.L2_core_bound},
+		{3, "L1 Bound", "Memory Bound", l3.L3_l1_bound},
+		{3, "L2 Bound", "Memory Bound", l3.L3_l2_bound},
+		{3, "L3 Bound", "Memory Bound", l3.L3_l3_bound},
+		{3, "DRAM Bound", "Memory Bound", l3.L3_dram_bound},
+		{3, "Divider", "Core Bound", l3.L3_divider},
+		{3, "Ports Utilization", "Core Bound", l3.L3_ports_utilization},
+	}
+}
+
+// parentShare returns the share of a metric in its parent one level up, in percent.
+func parentShare(rows []tmaRow, row tmaRow) float64 {
+	for _, p := range rows {
+		if p.Level == row.Level-1 && p.Name == row.Parent && p.Value > 1e-9 {
+			return row.Value / p.Value * 100
+		}
+	}
+	return 0
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// formatValue formats the value of an entry, or what gem5 wrote when it is
+// undefined.
+func formatValue(entry Entry) string {
+	if entry.Undefined {
+		return entry.Raw
+	}
+	return formatFloat(entry.Value)
+}
+
+// rawOr returns what gem5 wrote for an undefined number, or formatted when
+// the number is defined.
+func rawOr(raw string, formatted string) string {
+	if raw != "" {
+		return raw
+	}
+	return formatted
+}
+
+// formatPercentages formats the percentages of an entry, or what gem5 wrote
+// for the undefined ones. Both are empty when the entry has none.
+func formatPercentages(entry Entry) (string, string) {
+	if !entry.HasPercentage {
+		return "", ""
+	}
+	p1, p2 := formatFloat(entry.Percentage1), formatFloat(entry.Percentage2)
+	if entry.RawPercentage1 != "" {
+		p1 = entry.RawPercentage1
+	}
+	if entry.RawPercentage2 != "" {
+		p2 = entry.RawPercentage2
+	}
+	return p1, p2
+}
+
+func (w TextWriter) Write(dumps []Dump, out io.Writer) error {
+	writer := bufio.NewWriter(out)
+	for _, dump := range dumps {
+		if len(dumps) > 1 {
+			fmt.Fprintf(writer, "---------- Dump %d ----------\n", dump.Index)
+		}
+		for _, entry := range sortedEntries(dump.Entries) {
+			value := fmt.Sprintf("%f", entry.Value)
+			if entry.Undefined {
+				value = entry.Raw
+			}
+			if entry.HasPercentage {
+				p1, p2 := fmt.Sprintf("%f", entry.Percentage1), fmt.Sprintf("%f", entry.Percentage2)
+				if entry.RawPercentage1 != "" {
+					p1 = entry.RawPercentage1
+				}
+				if entry.RawPercentage2 != "" {
+					p2 = entry.RawPercentage2
+				}
+				fmt.Fprintf(writer, "%s %s %s%% %s%% %s\n", entry.Name, value, p1, p2, entry.Description)
+			} else {
+				fmt.Fprintf(writer, "%s %s %s\n", entry.Name, value, entry.Description)
+			}
+		}
+	}
+	return writer.Flush()
+}
+
+func (w CsvWriter) Write(dumps []Dump, out io.Writer) error {
+	writer := csv.NewWriter(out)
+	writer.UseCRLF = true
+
+	writer.Write([]string{"dump", "core", "section", "name", "parent", "value", "percentage", "cumulative_percentage", "description"})
+
+	for _, param := range paramRows(dumpParams(dumps)) {
+		writer.Write([]string{"", "", "param", param[0], "", param[1], "", "", ""})
+	}
+
+	for _, dump := range dumps {
+		index := strconv.Itoa(dump.Index)
+
+		if iv := dump.Interval; iv != nil && len(dumps) > 1 {
+			writer.Write([]string{index, "", "interval", "Start Tick", "", strconv.FormatUint(iv.StartTick, 10), "", "", ""})
+			writer.Write([]string{index, "", "interval", "End Tick", "", strconv.FormatUint(iv.EndTick, 10), "", "", ""})
+			for _, row := range tmaRows(iv.Stats) {
+				section := fmt.Sprintf("interval_l%d", row.Level)
+				writer.Write([]string{index, "", section, row.Name, row.Parent, formatFloat(row.Value), formatFloat(row.Value * 100), "", ""})
+			}
+		}
+
+		writeCsvTMA(writer, index, "", dump.Stats)
+		if dump.Stats != nil {
+			writeCsvMetrics(writer, index, "", dump.Stats.metrics)
+		}
+		for _, c := range CompareTMA(dump.Stats) {
+			writer.Write([]string{index, c.Core, "compare", c.Category, "computed", formatFloat(c.Computed), "", "", ""})
+			writer.Write([]string{index, c.Core, "compare", c.Category, "gem5", formatFloat(c.GEM5), "", "", ""})
+			writer.Write([]string{index, c.Core, "compare", c.Category, "abs_error", formatFloat(c.AbsError), "", "", ""})
+			writer.Write([]string{index, c.Core, "compare", c.Category, "rel_error", formatFloat(c.RelError), "", "", ""})
+		}
+		for _, w := range dump.Stats.allWarnings() {
+			writer.Write([]string{index, w.Core, "warning", w.Metric, w.Kind, "", "", "", w.Message})
+		}
+		if dump.Stats != nil && len(dump.Stats.cores) > 1 {
+			for _, core := range dump.Stats.cores {
+				writeCsvTMA(writer, index, core.core, core)
+				writeCsvMetrics(writer, index, core.core, core.metrics)
+			}
+		}
+
+		for _, entry := range sortedEntries(dump.Entries) {
+			p1, p2 := formatPercentages(entry)
+			writer.Write([]string{index, "", "stat", entry.Name, "", formatValue(entry), p1, p2, entry.Description})
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func writeCsvTMA(writer *csv.Writer, index string, core string, stats *TMAStats) {
+	rows := tmaRows(stats)
+	for _, row := range rows {
+		section := fmt.Sprintf("tma_l%d", row.Level)
+		share := row.Value * 100
+		if row.Level > 1 {
+			share = parentShare(rows, row)
+		}
+		writer.Write([]string{index, core, section, row.Name, row.Parent, formatFloat(row.Value), formatFloat(share), "", ""})
+	}
+}
+
+// mdEscape keeps a cell from breaking the surrounding Markdown table.
+func mdEscape(s string) string {
+	return strings.ReplaceAll(s, "|", "\\|")
+}
+
+func (w MdWriter) Write(dumps []Dump, out io.Writer) error {
+	writer := bufio.NewWriter(out)
+
+	if params := paramRows(dumpParams(dumps)); params != nil {
+		fmt.Fprintln(writer, "## TMA Model Parameters")
+		fmt.Fprintln(writer)
+		fmt.Fprintln(writer, "| Parameter | Value |")
+		fmt.Fprintln(writer, "| --- | ---: |")
+		for _, param := range params {
+			fmt.Fprintf(writer, "| %s | %s |\n", param[0], mdEscape(param[1]))
+		}
+		fmt.Fprintln(writer)
+	}
+
+	if len(dumps) > 1 {
+		writeMdTimeSeries(writer, dumps)
+	}
+
+	for _, dump := range dumps {
+		if len(dumps) > 1 {
+			fmt.Fprintf(writer, "# Dump %d\n\n", dump.Index)
+		}
+		writeMdDump(writer, dump)
+	}
+
+	return writer.Flush()
+}
+
+// writeMdTimeSeries renders one row per interval with every L1/L2 metric as a column.
+func writeMdTimeSeries(writer *bufio.Writer, dumps []Dump) {
+	header := false
+	for _, dump := range dumps {
+		if dump.Interval == nil {
+			continue
+		}
+		rows := tmaRows(dump.Interval.Stats)
+		if !header {
+			fmt.Fprintln(writer, "# Time Series")
+			fmt.Fprintln(writer)
+			fmt.Fprint(writer, "| Dump | Start Tick | End Tick |")
+			for _, row := range rows {
+				fmt.Fprintf(writer, " %s |", row.Name)
+			}
+			fmt.Fprintln(writer)
+			fmt.Fprint(writer, "| ---: | ---: | ---: |")
+			fmt.Fprintln(writer, strings.Repeat(" ---: |", len(rows)))
+			header = true
+		}
+		fmt.Fprintf(writer, "| %d | %d | %d |", dump.Index, dump.Interval.StartTick, dump.Interval.EndTick)
+		for _, row := range rows {
+			fmt.Fprintf(writer, " %.4f |", row.Value)
+		}
+		fmt.Fprintln(writer)
+	}
+	if header {
+		fmt.Fprintln(writer)
+	}
+}
+
+// writeMdCores renders one row per core with every L1/L2 metric as a column.
+func writeMdCores(writer *bufio.Writer, cores []*TMAStats) {
+	fmt.Fprintln(writer, "## TMA per Core")
+	fmt.Fprintln(writer)
+	for i, core := range cores {
+		rows := tmaRows(core)
+		if i == 0 {
+			fmt.Fprint(writer, "| Core |")
+			for _, row := range rows {
+				fmt.Fprintf(writer, " %s |", row.Name)
+			}
+			fmt.Fprintln(writer)
+			fmt.Fprint(writer, "| --- |")
+			fmt.Fprintln(writer, strings.Repeat(" ---: |", len(rows)))
+		}
+		fmt.Fprintf(writer, "| %s |", core.core)
+		for _, row := range rows {
+			fmt.Fprintf(writer, " %.4f |", row.Value)
+		}
+		fmt.Fprintln(writer)
+	}
+	fmt.Fprintln(writer)
+}
+
+func writeMdDump(writer *bufio.Writer, dump Dump) {
+	if dump.Stats != nil && dump.Stats.metrics != nil {
+		writeMdMetrics(writer, dump.Stats.metrics)
+	}
+
+	rows := tmaRows(dump.Stats)
+	if rows != nil {
+		fmt.Fprintln(writer, "## TMA Level 1")
+		fmt.Fprintln(writer)
+		fmt.Fprintln(writer, "| Category | Value | Percentage |")
+		fmt.Fprintln(writer, "| --- | ---: | ---: |")
+		for _, row := range rows {
+			if row.Level == 1 {
+				fmt.Fprintf(writer, "| %s | %.4f | %.2f%% |\n", row.Name, row.Value, row.Value*100)
+			}
+		}
+		fmt.Fprintln(writer)
+
+		fmt.Fprintln(writer, "## TMA Level 2")
+		fmt.Fprintln(writer)
+		fmt.Fprintln(writer, "| Category | Parent | Value | Share of Parent |")
+		fmt.Fprintln(writer, "| --- | --- | ---: | ---: |")
+		for _, row := range rows {
+			if row.Level == 2 {
+				fmt.Fprintf(writer, "| %s | %s | %.4f | %.1f%% |\n", row.Name, row.Parent, row.Value, parentShare(rows, row))
+			}
+		}
+		fmt.Fprintln(writer)
+
+		fmt.Fprintln(writer, "## TMA Level 3")
+		fmt.Fprintln(writer)
+		fmt.Fprintln(writer, "| Category | Parent | Value | Share of Parent |")
+		fmt.Fprintln(writer, "| --- | --- | ---: | ---: |")
+		for _, row := range rows {
+			if row.Level == 3 {
+				fmt.Fprintf(writer, "| %s | %s | %.4f | %.1f%% |\n", row.Name, row.Parent, row.Value, parentShare(rows, row))
+			}
+		}
+		fmt.Fprintln(writer)
+	}
+
+	if dump.Stats != nil && len(dump.Stats.cores) > 1 {
+		writeMdCores(writer, dump.Stats.cores)
+	}
+
+	if comparisons := CompareTMA(dump.Stats); len(comparisons) > 0 {
+		fmt.Fprintln(writer, "## Calculated vs GEM5 TMA Level 1")
+		fmt.Fprintln(writer)
+		fmt.Fprintln(writer, "| Core | Category | Computed | GEM5 | Abs Error | Rel Error |")
+		fmt.Fprintln(writer, "| --- | --- | ---: | ---: | ---: | ---: |")
+		for _, c := range comparisons {
+			core := c.Core
+			if core == "" {
+				core = "all"
+			}
+			fmt.Fprintf(writer, "| %s | %s | %.4f | %.4f | %.4f | %.1f%% |\n", core, c.Category, c.Computed, c.GEM5, c.AbsError, c.RelError*100)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	if warnings := dump.Stats.allWarnings(); len(warnings) > 0 {
+		fmt.Fprintln(writer, "## Warnings")
+		fmt.Fprintln(writer)
+		for _, w := range warnings {
+			fmt.Fprintf(writer, "- %s\n", w)
+		}
+		fmt.Fprintln(writer)
+	}
+
+	if vectors := GroupVectors(dump.Entries); len(vectors) > 0 {
+		writeMdVectors(writer, vectors)
+	}
+
+	if dists := GroupDistributions(dump.Entries); len(dists) > 0 {
+		writeMdDistributions(writer, dists)
+	}
+
+	fmt.Fprintln(writer, "## Selected Statistics")
+	fmt.Fprintln(writer)
+	fmt.Fprintln(writer, "| Name | Value | Percentage | Cumulative | Description |")
+	fmt.Fprintln(writer, "| --- | ---: | ---: | ---: | --- |")
+	for _, entry := range sortedEntries(dump.Entries) {
+		p1, p2 := formatPercentages(entry)
+		if entry.HasPercentage {
+			p1, p2 = p1+"%", p2+"%"
+		}
+		fmt.Fprintf(writer, "| %s | %s | %s | %s | %s |\n",
+			mdEscape(entry.Name), formatValue(entry), p1, p2, mdEscape(entry.Description))
+	}
+	fmt.Fprintln(writer)
+}
+
+func (w JsonWriter) Write(dumps []Dump, out io.Writer) error {
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(newReport(dumps))
+}
+
+func (w YamlWriter) Write(dumps []Dump, out io.Writer) error {
+	// Going through JSON keeps a single set of struct tags and the field order.
+	doc, err := json.Marshal(newReport(dumps))
+	if err != nil {
+		return err
+	}
+	var node yaml.Node
+	if err := yaml.Unmarshal(doc, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+
+	encoder := yaml.NewEncoder(out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&node); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle drops the flow style that the JSON input leaves on every node.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// NewWriter returns the DataWriter registered for the given -format value.
+func NewWriter(format string) (DataWriter, error) {
+	switch strings.ToLower(format) {
+	case "markdown", "md":
+		return MdWriter{}, nil
+	case "csv":
+		return CsvWriter{}, nil
+	case "json":
+		return JsonWriter{}, nil
+	case "yaml", "yml":
+		return YamlWriter{}, nil
+	case "text", "txt":
+		return TextWriter{}, nil
+	case "html", "htm":
+		return HtmlWriter{}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q", format)
+}
+
+// sortedEntries returns the entries ordered by stat name so output is stable.
+func sortedEntries(entries map[string]Entry) []Entry {
+	sorted := make([]Entry, 0, len(entries))
+	for _, entry := range entries {
+		sorted = append(sorted, entry)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+	return sorted
+}
+
+func PrintCalcStats(w io.Writer, stats *TMAStats) {
+	if stats == nil {
+		fmt.Fprintln(w, "Stats is nil!")
+		return
+	}
+
+	l1 := stats.tmaL1
+	l2 := stats.tmaL2
+	l3 := stats.tmaL3
+
+	fmt.Fprintln(w, "==================== Calculated TMA Level 1 Stats ====================")
+	fmt.Fprintf(w, "  %-20s  %8.4f  (%6.2f%%)\n", "Retiring:", l1.L1_retire, l1.L1_retire*100)
+	fmt.Fprintf(w, "  %-20s  %8.4f  (%6.2f%%)\n", "Bad Speculation:", l1.L1_badspec, l1.L1_badspec*100)
+	fmt.Fprintf(w, "  %-20s  %8.4f  (%6.2f%%)\n", "Frontend Bound:", l1.L1_frontend, l1.L1_frontend*100)
+	fmt.Fprintf(w, "  %-20s  %8.4f  (%6.2f%%)\n", "Backend Bound:", l1.L1_backend, l1.L1_backend*100)
+
+	fmt.Fprintln(w, "\n==================== Calculated TMA Level 2 Stats (Breakdown) ====================")
+
+	printL2 := func(metricName string, value float64, parentName string, parentValue float64) {
+		percentageOfParent := 0.0
+		if parentValue > 1e-9 {
+			percentageOfParent = (value / parentValue) * 100
+		}
+
+		fmt.Fprintf(w, "  %-22s %8.4f  ( %-15s: %5.1f%%)\n",
+			metricName+":", value, parentName, percentageOfParent)
+	}
+
+	fmt.Fprintln(w, "  --- Frontend Bound Breakdown ---")
+	printL2("Fetch Latency", l2.L2_fetch_latency, "Frontend Bound", l1.L1_frontend)
+	printL2("Fetch Bandwidth", l2.L2_fetch_bandwidth, "Frontend Bound", l1.L1_frontend)
+
+	fmt.Fprintln(w, "  --- Bad Speculation Breakdown ---")
+	printL2("Branch Mispred", l2.L2_branch_mispredict, "Bad Speculation", l1.L1_badspec)
+	printL2("Machine Clears", l2.L2_machine_clear, "Bad Speculation", l1.L1_badspec)
+
+	fmt.Fprintln(w, "  --- Backend Bound Breakdown ---")
+	printL2("Memory Bound", l2.L2_memory_bound, "Backend Bound", l1.L1_backend)
+	printL2("Core Bound", l2.L2_core_bound, "Backend Bound", l1.L1_backend)
+
+	fmt.Fprintln(w, "\n==================== Calculated TMA Level 3 Stats (Breakdown) ====================")
+
+	fmt.Fprintln(w, "  --- Memory Bound Breakdown ---")
+	printL2("L1 Bound", l3.L3_l1_bound, "Memory Bound", l2.L2_memory_bound)
+	printL2("L2 Bound", l3.L3_l2_bound, "Memory Bound", l2.L2_memory_bound)
+	printL2("L3 Bound", l3.L3_l3_bound, "Memory Bound", l2.L2_memory_bound)
+	printL2("DRAM Bound", l3.L3_dram_bound, "Memory Bound", l2.L2_memory_bound)
+
+	fmt.Fprintln(w, "  --- Core Bound Breakdown ---")
+	printL2("Divider", l3.L3_divider, "Core Bound", l2.L2_core_bound)
+	printL2("Ports Utilization", l3.L3_ports_utilization, "Core Bound", l2.L2_core_bound)
+
+	if len(stats.cores) > 1 {
+		fmt.Fprintln(w, "\n==================== Calculated TMA Level 1 per Core ====================")
+		fmt.Fprintf(w, "  %-8s  %8s  %8s  %8s  %8s\n", "Core", "Retire", "BadSpec", "Frontend", "Backend")
+		for _, core := range stats.cores {
+			fmt.Fprintf(w, "  %-8s  %8.4f  %8.4f  %8.4f  %8.4f\n", core.core,
+				core.tmaL1.L1_retire, core.tmaL1.L1_badspec, core.tmaL1.L1_frontend, core.tmaL1.L1_backend)
+		}
+	}
+
+	fmt.Fprintln(w, "")
+}
+
+// PrintTimeSeries prints the L1 breakdown of every interval on one line.
+func PrintTimeSeries(w io.Writer, dumps []Dump) {
+	fmt.Fprintln(w, "==================== TMA Level 1 Time Series ====================")
+	fmt.Fprintf(w, "  %4s  %14s  %14s  %8s  %8s  %8s  %8s\n", "Dump", "Start Tick", "End Tick", "Retire", "BadSpec", "Frontend", "Backend")
+	for _, dump := range dumps {
+		if dump.Interval == nil {
+			continue
+		}
+		l1 := dump.Interval.Stats.tmaL1
+		fmt.Fprintf(w, "  %4d  %14d  %14d  %8.4f  %8.4f  %8.4f  %8.4f\n", dump.Index,
+			dump.Interval.StartTick, dump.Interval.EndTick,
+			l1.L1_retire, l1.L1_badspec, l1.L1_frontend, l1.L1_backend)
+	}
+	fmt.Fprintln(w, "")
+}
+
+func PrintPMUStats(w io.Writer, stats *TMAStats) {
+	if stats == nil {
+		fmt.Fprintln(w, "Stats is nil!")
+		return
+	}
+
+	t := stats.mytma
+
+	fmt.Fprintln(w, "==================== Raw TMA Metrics Collected from GEM5 ====================")
+	fmt.Fprintf(w, "  L1_retire:           %.4f\n", t.L1_retire)
+	fmt.Fprintf(w, "  L1_badspec:          %.4f\n", t.L1_badspec)
+	fmt.Fprintf(w, "  L1_frontend:         %.4f\n", t.L1_frontend)
+	fmt.Fprintf(w, "  L1_backend:          %.4f\n", t.L1_backend)
+	fmt.Fprintf(w, "  L0_fullfrontend:     %.4f\n", t.L0_fullfrontend)
+	fmt.Fprintf(w, "  L0_frontendutil:     %.4f\n", t.L0_frontendutil)
+	fmt.Fprintf(w, "  L0_branchprediction: %.4f\n", t.L0_BranchPrediction)
+}
+
+// PrintReport prints the console summary of the analysed dumps: parameters,
+// the per dump results and the time series.
+func PrintReport(w io.Writer, dumps []Dump) {
+	if params := dumpParams(dumps); params != nil {
+		PrintParams(w, params)
+	}
+	for _, dump := range dumps {
+		if len(dumps) > 1 {
+			fmt.Fprintf(w, "#################### Dump %d ####################\n\n", dump.Index)
+		}
+		if dump.Stats != nil {
+			PrintMetrics(w, dump.Stats.metrics)
+		}
+		PrintCalcStats(w, dump.Stats)
+		PrintPMUStats(w, dump.Stats)
+		PrintVectors(w, GroupVectors(dump.Entries))
+		PrintDistributions(w, GroupDistributions(dump.Entries))
+		PrintComparison(w, CompareTMA(dump.Stats))
+		PrintWarnings(w, dump.Stats.allWarnings())
+	}
+	if len(dumps) > 1 {
+		PrintTimeSeries(w, dumps)
+	}
+}
+
+// WriteData writes the dumps to w in the given format (see NewWriter).
+func WriteData(w io.Writer, dumps []Dump, format string) error {
+	writer, err := NewWriter(format)
+	if err != nil {
+		return err
+	}
+	return writer.Write(dumps, w)
+}
@@ -1,3 +1,5 @@
 module go_gem5_parser
 
 go 1.25.5
+
+require gopkg.in/yaml.v3 v3.0.1
@@ -2,39 +2,174 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"log"
 	"os"
+	"path/filepath"
+
+	"go_gem5_parser/gem5stats"
 )
 
-type Entry struct {
-	Name          string
-	Value         float64
-	Percentage1   float64
-	Percentage2   float64
-	Description   string
-	HasPercentage bool
+func fileExists(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && !info.IsDir()
 }
 
-type Stats struct {
+// loadInterest reads the interests file, there has to be at least one name.
+func loadInterest(InterestFile *string) *gem5stats.Interest {
+	file, err := os.Open(*InterestFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+
+	Interests, InterestCount, err := gem5stats.GetInterest(file)
+	if err != nil {
+		log.Fatalf("%s: %v", *InterestFile, err)
+	}
+	if InterestCount == 0 {
+		log.Fatal("No interested items found in the interest file!")
+	}
+	fmt.Println("Found", InterestCount, "interested items.")
+	fmt.Println()
+	return Interests
+}
+
+// readParams builds the model parameters of one stats file: the defaults, then
+// the gem5 config.json (given, or found next to the stats file), then the
+// -uarch file.
+func readParams(StatsFile string, ConfigFile string, ParamsFile string) (*gem5stats.UarchParams, error) {
+	Params := gem5stats.DefaultParams()
+	configFile := ConfigFile
+	if configFile == "" {
+		if path := filepath.Join(filepath.Dir(StatsFile), "config.json"); fileExists(path) {
+			configFile = path
+		}
+	}
+
+	overlay := func(path string, load func(*os.File) (*gem5stats.UarchParams, error)) error {
+		file, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		Params, err = load(file)
+		return err
+	}
+	if configFile != "" {
+		err := overlay(configFile, func(file *os.File) (*gem5stats.UarchParams, error) {
+			return gem5stats.LoadGem5Config(file, configFile, Params)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if ParamsFile != "" {
+		err := overlay(ParamsFile, func(file *os.File) (*gem5stats.UarchParams, error) {
+			return gem5stats.LoadParams(file, ParamsFile, Params)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Params, nil
 }
 
-type DataWriter interface {
-	Write(entries []Entry, filepath *os.File) error
+// loadParams is readParams for a single run, which cannot go on without them.
+func loadParams(StatsFile *string, ConfigFile *string, ParamsFile *string) *gem5stats.UarchParams {
+	Params, err := readParams(*StatsFile, *ConfigFile, *ParamsFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return Params
+}
+
+// analyseStats parses the stats file and runs the TMA analysis on every dump.
+func analyseStats(StatsFile string, Interests *gem5stats.Interest, Params *gem5stats.UarchParams, ProfileName string) ([]gem5stats.Dump, *gem5stats.Profile, error) {
+	file, err := gem5stats.OpenStats(StatsFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer file.Close()
+
+	Dumps, Profile, err := gem5stats.Analyse(file, Interests, Params, ProfileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", StatsFile, err)
+	}
+	return Dumps, Profile, nil
+}
+
+// analyseFile is analyseStats telling what it found on the console.
+func analyseFile(Interests *gem5stats.Interest, StatsFile *string, Params *gem5stats.UarchParams, ProfileName *string) []gem5stats.Dump {
+	Dumps, Profile, err := analyseStats(*StatsFile, Interests, Params, *ProfileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(Dumps) > 1 {
+		fmt.Println("Found", len(Dumps), "stats dumps in", *StatsFile)
+		fmt.Println()
+	}
+	fmt.Println("Using stat profile", Profile.Name, "for", *StatsFile)
+	fmt.Println()
+	return Dumps
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiff(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "batch" {
+		runBatch(os.Args[2:])
+		return
+	}
+
 	var InterestFile = flag.String("interest", "interests.txt", "The (relative path to) file that contain interested data")
-	var StatsFile = flag.String("stats", "m5out/stats.txt", "The (relative path to) file that contain stats.txt")
+	var StatsFile = flag.String("stats", "m5out/stats.txt", "The (relative path to) file that contain stats.txt, may be gzip compressed (stats.txt.gz)")
 	var OutFile = flag.String("out", "out.md", "The (relative path to) the output file")
-	var Format = flag.String("format", "Markdown", "The default output type of the file")
+	var Format = flag.String("format", "Markdown", "The output format: Markdown, CSV, JSON, YAML, Text or HTML")
+	var Cumulative = flag.Bool("cumulative", false, "Counters are cumulative across dumps (dumpstats without reset), analyse the deltas between dumps")
+	var ParamsFile = flag.String("uarch", "", "The (relative path to) JSON file with the microarchitecture parameters of the TMA model")
+	var ProfileName = flag.String("profile", "auto", "The stat name profile: auto, stdlib-ruby, stdlib-classic, se-classic or the path to a JSON profile")
+	var MaxDivergence = flag.Float64("max-divergence", 0, "Fail when a calculated L1 category differs from gem5's own by more than this (absolute fraction), 0 disables the check")
+	var ConfigFile = flag.String("config", "", "The (relative path to) gem5 config.json, defaults to config.json next to the stats file")
+	var TUI = flag.Bool("tui", false, "Browse the TMA tree and its counters in an interactive terminal view instead of printing the results")
 	flag.Parse()
 
-	InterestMap, len := GetInterest(InterestFile)
-	if len == 0 {
-		log.Fatal("No interested items found in the interest file!")
+	Params := loadParams(StatsFile, ConfigFile, ParamsFile)
+	Interests := loadInterest(InterestFile)
+
+	Dumps := analyseFile(Interests, StatsFile, Params, ProfileName)
+	gem5stats.GetTimeSeries(Dumps, *Cumulative, Params)
+
+	writer, err := gem5stats.NewWriter(*Format)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if !*TUI {
+		gem5stats.PrintReport(os.Stdout, Dumps)
 	}
 
-	AllEntries := Parselines(&InterestMap, StatsFile, len)
+	file, err := os.Create(*OutFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer file.Close()
+	if err := writer.Write(Dumps, file); err != nil {
+		log.Fatal(err)
+	}
+	if *TUI {
+		runTUI(Dumps, *StatsFile, *Cumulative)
+	}
 
-	WriteData(OutFile, AllEntries, Format, GetStats(&AllEntries))
-}
\ No newline at end of file
+	if *MaxDivergence > 0 {
+		if worst, dump, ok := gem5stats.WorstDivergence(Dumps); ok && worst.AbsError > *MaxDivergence {
+			core := worst.Core
+			if core == "" {
+				core = "all cores"
+			}
+			log.Fatalf("%s of dump %d (%s) diverges from gem5 by %.4f (computed %.4f, gem5 %.4f), more than %.4f",
+				worst.Category, dump, core, worst.AbsError, worst.Computed, worst.GEM5, *MaxDivergence)
+		}
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"go_gem5_parser/gem5stats"
+)
+
+// stty runs stty on the terminal of stdin and returns what it printed.
+func stty(args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = os.Stdin
+	out, err := cmd.Output()
+	return strings.TrimSpace(string(out)), err
+}
+
+// terminalSize returns the rows and columns of the terminal, 24x80 when stty
+// cannot tell.
+func terminalSize() (int, int) {
+	out, err := stty("size")
+	if fields := strings.Fields(out); err == nil && len(fields) == 2 {
+		rows, err1 := strconv.Atoi(fields[0])
+		cols, err2 := strconv.Atoi(fields[1])
+		if err1 == nil && err2 == nil && rows > 0 && cols > 0 {
+			return rows, cols
+		}
+	}
+	return 24, 80
+}
+
+// tuiLine is one line of the tree view: a category, or one of its counters
+// when counter is set.
+type tuiLine struct {
+	node    *gem5stats.TMANode
+	counter *gem5stats.Counter
+}
+
+// tui is the state of the interactive TMA view.
+type tui struct {
+	file     string
+	dumps    []gem5stats.Dump
+	interval bool            // Show the TMA of the interval instead of the whole dump
+	dump     int             // Index into dumps
+	core     int             // 0 for all cores, else 1 + the index into Cores()
+	cursor   int             // Selected category among the visible ones
+	offset   int             // First line shown when the tree does not fit
+	expanded map[string]bool // Categories showing their breakdown, by name
+	counters map[string]bool // Categories showing their PMU counters, by name
+}
+
+// shown returns the stats of the dump or of its interval, for all cores.
+func (t *tui) shown() *gem5stats.TMAStats {
+	dump := t.dumps[t.dump]
+	if t.interval && dump.Interval != nil {
+		return dump.Interval.Stats
+	}
+	return dump.Stats
+}
+
+func (t *tui) stats() *gem5stats.TMAStats {
+	stats := t.shown()
+	if cores := stats.Cores(); t.core > 0 && t.core <= len(cores) {
+		return cores[t.core-1]
+	}
+	return stats
+}
+
+// lines lists the visible part of the tree, depth first.
+func (t *tui) lines() []tuiLine {
+	var lines []tuiLine
+	var walk func(nodes []*gem5stats.TMANode)
+	walk = func(nodes []*gem5stats.TMANode) {
+		for _, node := range nodes {
+			lines = append(lines, tuiLine{node: node})
+			if t.counters[node.Name] {
+				for i := range node.Counters {
+					lines = append(lines, tuiLine{node: node, counter: &node.Counters[i]})
+				}
+			}
+			if t.expanded[node.Name] {
+				walk(node.Children)
+			}
+		}
+	}
+	walk(gem5stats.TMATree(t.stats()))
+	return lines
+}
+
+// categories returns the category lines of lines, the ones the cursor moves on.
+func categories(lines []tuiLine) []*gem5stats.TMANode {
+	var nodes []*gem5stats.TMANode
+	for _, line := range lines {
+		if line.counter == nil {
+			nodes = append(nodes, line.node)
+		}
+	}
+	return nodes
+}
+
+// setAll expands or collapses every category that has a breakdown.
+func (t *tui) setAll(nodes []*gem5stats.TMANode, expand bool) {
+	for _, node := range nodes {
+		if len(node.Children) > 0 {
+			t.expanded[node.Name] = expand
+			t.setAll(node.Children, expand)
+		}
+	}
+}
+
+// handle applies one key press and reports whether the view stays open.
+func (t *tui) handle(key string) bool {
+	nodes := categories(t.lines())
+	if len(nodes) == 0 {
+		return key != "q" && key != "\x1b" && key != "\x03"
+	}
+	selected := nodes[t.cursor]
+
+	switch key {
+	case "q", "\x1b", "\x03":
+		return false
+	case "\x1b[A", "k":
+		t.cursor--
+	case "\x1b[B", "j":
+		t.cursor++
+	case "\x1b[C", "l":
+		if len(selected.Children) > 0 {
+			t.expanded[selected.Name] = true
+		}
+	case "\x1b[D", "h":
+		if t.expanded[selected.Name] {
+			t.expanded[selected.Name] = false
+			break
+		}
+		// Jump to the category this one breaks down.
+		for i := t.cursor - 1; i >= 0; i-- {
+			if nodes[i].Level == selected.Level-1 {
+				t.cursor = i
+				break
+			}
+		}
+	case " ":
+		if len(selected.Children) > 0 {
+			t.expanded[selected.Name] = !t.expanded[selected.Name]
+		}
+	case "\r", "\n":
+		t.counters[selected.Name] = !t.counters[selected.Name]
+	case "e":
+		t.setAll(gem5stats.TMATree(t.stats()), true)
+	case "E":
+		t.setAll(gem5stats.TMATree(t.stats()), false)
+	case "n", "\x1b[6~":
+		t.dump = min(t.dump+1, len(t.dumps)-1)
+	case "p", "\x1b[5~":
+		t.dump = max(t.dump-1, 0)
+	case "\t":
+		// The intervals of cumulative dumps are not split by core, so Tab does
+		// nothing on those.
+		if cores := t.shown().Cores(); len(cores) > 1 {
+			t.core = (t.core + 1) % (len(cores) + 1)
+		}
+	}
+	// Back to all cores when the dump now shown lacks the selected one.
+	if t.core > len(t.shown().Cores()) {
+		t.core = 0
+	}
+
+	nodes = categories(t.lines())
+	t.cursor = max(0, min(t.cursor, len(nodes)-1))
+	return true
+}
+
+// formatCounter prints counts as integers and means with two decimals.
+func formatCounter(v float64) string {
+	if v == math.Trunc(v) {
+		return strconv.FormatFloat(v, 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// bar draws a fraction of the pipeline slots width characters wide.
+func bar(value float64, width int) string {
+	n := int(math.Round(math.Max(0, math.Min(1, value)) * float64(width)))
+	return strings.Repeat("█", n) + strings.Repeat("·", width-n)
+}
+
+// render draws the whole screen. Lines end in \r\n since the terminal is raw.
+func (t *tui) render(w io.Writer) {
+	rows, cols := terminalSize()
+	stats := t.stats()
+	dump := t.dumps[t.dump]
+
+	core := "all cores"
+	if t.core > 0 {
+		core = stats.Core()
+	}
+	header := []string{
+		fmt.Sprintf("%s  dump %d/%d  %s", t.file, t.dump+1, len(t.dumps), core),
+	}
+	if dump.Interval != nil {
+		header[0] += fmt.Sprintf("  ticks %d-%d", dump.Interval.StartTick, dump.Interval.EndTick)
+	}
+	if m := stats.Metrics(); m != nil {
+		header = append(header, fmt.Sprintf("IPC %.3f  CPI %.3f  branch MPKI %.2f  L1D MPKI %.2f  L2 MPKI %.2f",
+			m.IPC, m.CPI, m.BranchMPKI, m.L1DMPKI, m.L2MPKI))
+	}
+	header = append(header, "")
+
+	footer := []string{
+		"",
+		fmt.Sprintf("%d warnings", len(stats.Warnings())),
+		"↑↓ move  →← expand/collapse  space toggle  enter counters  e/E all  n/p dump  tab core  q quit",
+	}
+
+	barWidth := max(10, min(40, cols-60))
+	lines := t.lines()
+	var body []string
+	selectedLine, category := 0, 0
+	for _, line := range lines {
+		node := line.node
+		indent := strings.Repeat("  ", node.Level-1)
+		if line.counter != nil {
+			body = append(body, fmt.Sprintf("  %s    %-24s %14s", indent, line.counter.Name, formatCounter(line.counter.Value)))
+			continue
+		}
+
+		marker := "  "
+		if len(node.Children) > 0 {
+			marker = "▸ "
+			if t.expanded[node.Name] {
+				marker = "▾ "
+			}
+		}
+		share := fmt.Sprintf("%5.1f%%", node.Share)
+		if node.Level > 1 {
+			share += " of parent"
+		}
+		text := fmt.Sprintf("%s%s%-*s %8.4f  %s  %s", indent, marker, 24-2*(node.Level-1), node.Name, node.Value, bar(node.Value, barWidth), share)
+		if category == t.cursor {
+			selectedLine = len(body)
+			text = "\x1b[7m" + text + "\x1b[0m"
+		}
+		category++
+		body = append(body, text)
+	}
+
+	// Scroll so that the selected category stays on screen.
+	height := max(1, rows-len(header)-len(footer))
+	if selectedLine < t.offset {
+		t.offset = selectedLine
+	}
+	if selectedLine >= t.offset+height {
+		t.offset = selectedLine - height + 1
+	}
+	t.offset = max(0, min(t.offset, len(body)-height))
+	body = body[t.offset:min(len(body), t.offset+height)]
+
+	fmt.Fprint(w, "\x1b[H\x1b[2J")
+	for _, line := range append(append(header, body...), footer...) {
+		fmt.Fprint(w, line, "\x1b[K\r\n")
+	}
+}
+
+// runTUI shows the TMA tree of the dumps until the user quits. With interval
+// set the TMA of each interval is shown, as for cumulative counters.
+func runTUI(dumps []gem5stats.Dump, file string, interval bool) {
+	saved, err := stty("-g")
+	if err != nil {
+		log.Fatal("-tui needs an interactive terminal on stdin")
+	}
+	if _, err := stty("raw", "-echo"); err != nil {
+		log.Fatalf("cannot switch the terminal to raw mode: %v", err)
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	// Alternate screen without cursor, restored on the way out.
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l")
+	defer func() {
+		fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+		out.Flush()
+		stty(saved)
+	}()
+
+	t := &tui{
+		file:     file,
+		dumps:    dumps,
+		interval: interval,
+		dump:     len(dumps) - 1,
+		expanded: make(map[string]bool),
+		counters: make(map[string]bool),
+	}
+	key := make([]byte, 16)
+	for {
+		t.render(out)
+		out.Flush()
+		n, err := os.Stdin.Read(key)
+		if err != nil || !t.handle(string(key[:n])) {
+			return
+		}
+	}
+}